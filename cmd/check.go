@@ -0,0 +1,264 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/core"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/core/tag"
+	mdTemplate "github.com/Tiffinger-Thiel-GmbH/atwhy/template"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+var ErrOutdated = errors.New("the generated documentation is out of date, run atwhy to update it")
+
+// @WHY readme_usage2_check
+//
+// __Check__
+// To check in a CI if the generated documentation is up to date, run:
+// ```bash
+// atwhy check
+// ```
+// It does not write anything but prints a diff for each outdated file
+// together with the tags found in the changed lines and exits with a non-zero exit code.
+// Lines which only differ by the time of `{{"{{ .Now }}"}}` are not reported.
+
+// checkCmd generates the documentation in memory and compares it with the existing files.
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Checks if the generated documentation is up to date.",
+	Long: `Checks if the generated documentation is up to date.
+It runs the same generation as atwhy itself but instead of writing the files,
+it compares the result with the existing files.
+For each outdated file a diff and the tags found in each changed part are printed
+and the command exits with a non-zero exit code.
+
+Lines which only differ by the time of {{ .Now }} are not reported.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := LoadCommonArgs(cmd)
+		if err != nil {
+			return err
+		}
+
+		gen, err := NewGenerator(config.Generator)
+		if err != nil {
+			return err
+		}
+
+		atwhy, err := core.New(gen, config.ProjectPath, "/", config.TemplatesFolder, config.Extensions, config.CommentConfig, config.CoreOptions())
+		if err != nil {
+			return err
+		}
+
+		templates, err := load(cmd, &atwhy, config.Strict)
+		if err != nil {
+			return err
+		}
+
+		outputFS := afero.NewBasePathFs(afero.NewOsFs(), filepath.Join(config.ProjectPath, config.Output))
+		outdated, err := check(&atwhy, templates, outputFS, config.TemplatesFolder, cmd.OutOrStdout())
+		if err != nil {
+			return err
+		}
+
+		if outdated > 0 {
+			return ErrOutdated
+		}
+		return nil
+	},
+}
+
+// check generates the templates and compares them with the files in the outputFS.
+// It writes a unified diff for each outdated file to the writer and returns
+// the number of outdated files.
+func check(atwhy *core.AtWhy, templates []mdTemplate.Markdown, outputFS afero.Fs, templateFolder string, writer io.Writer) (outdated int, err error) {
+	for _, t := range templates {
+		t.Now = time.Now()
+		generated := bytes.Buffer{}
+		err := atwhy.Generate(t, &generated)
+		if err != nil {
+			return 0, err
+		}
+
+		// Generate it a second time at another time to find the lines depending on {{ .Now }}.
+		later := t
+		later.Now = t.Now.AddDate(1, 1, 1).Add(time.Hour + time.Minute + time.Second)
+		generatedLater := bytes.Buffer{}
+		err = atwhy.Generate(later, &generatedLater)
+		if err != nil {
+			return 0, err
+		}
+
+		filename := atwhy.OutputFile(t)
+		existing, err := afero.ReadFile(outputFS, filename)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return 0, err
+		}
+
+		// Ignore differences in the line endings, e.g. caused by git on Windows.
+		existing = bytes.ReplaceAll(existing, []byte("\r\n"), []byte("\n"))
+		if bytes.Equal(existing, generated.Bytes()) {
+			continue
+		}
+
+		existingLines, generatedLines := difflib.SplitLines(string(existing)), difflib.SplitLines(generated.String())
+		if onlyTimeChanged(existingLines, generatedLines, difflib.SplitLines(generatedLater.String())) {
+			continue
+		}
+
+		outdated++
+		templateFile := filepath.Join(templateFolder, t.Path, t.Name+".tpl.md")
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        existingLines,
+			B:        generatedLines,
+			FromFile: filepath.ToSlash(filename),
+			ToFile:   filepath.ToSlash(filename) + " (generated from " + filepath.ToSlash(templateFile) + ")",
+			Context:  3,
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		if _, err := fmt.Fprintln(writer, diff); err != nil {
+			return 0, err
+		}
+
+		err = printChangedTags(writer, t, existingLines, generatedLines)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return outdated, nil
+}
+
+// onlyTimeChanged checks if the existing lines only differ from the generated ones in lines
+// which depend on the time, that is the lines which differ from the generatedLater lines.
+func onlyTimeChanged(existing []string, generated []string, generatedLater []string) bool {
+	if len(generated) != len(generatedLater) {
+		return false
+	}
+
+	for _, op := range difflib.NewMatcher(existing, generated).GetOpCodes() {
+		if op.Tag == 'e' {
+			continue
+		}
+		if op.Tag != 'r' || op.I2-op.I1 != op.J2-op.J1 {
+			return false
+		}
+		for j := op.J1; j < op.J2; j++ {
+			if generated[j] == generatedLater[j] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// printChangedTags writes for each hunk of the diff the tags whose value is in the changed lines.
+// Removed lines are attributed to the tags around them.
+// Nothing is written if no tag is found in any of the hunks.
+func printChangedTags(writer io.Writer, t mdTemplate.Markdown, existing []string, generated []string) error {
+	tagLines, err := t.TagLines()
+	if err != nil || tagLines == nil {
+		return err
+	}
+
+	// The lines of the executed template have to match the generated lines,
+	// which is not the case e.g. for the html generator.
+	executed := bytes.Buffer{}
+	if err := t.Execute(&executed); err != nil {
+		return err
+	}
+	if !strings.HasPrefix(strings.Join(generated, ""), executed.String()) {
+		return nil
+	}
+
+	tagsAt := func(line int) []tag.Tag {
+		if line < 0 || line >= len(tagLines) {
+			return nil
+		}
+		return tagLines[line]
+	}
+
+	var hunks []string
+	matcher := difflib.NewMatcher(existing, generated)
+	for _, group := range matcher.GetGroupedOpCodes(3) {
+		first, last := group[0], group[len(group)-1]
+
+		var found []string
+		seen := make(map[string]bool)
+		add := func(current tag.Tag) {
+			if !seen[current.Placeholder()] {
+				seen[current.Placeholder()] = true
+				found = append(found, tagName(current))
+			}
+		}
+
+		for _, op := range group {
+			switch op.Tag {
+			case 'r', 'i':
+				for line := op.J1; line < op.J2; line++ {
+					for _, current := range tagsAt(line) {
+						add(current)
+					}
+				}
+			case 'd':
+				// The lines were removed inside of a tag if it is before and after them.
+				after := make(map[string]bool)
+				for _, current := range tagsAt(op.J1) {
+					after[current.Placeholder()] = true
+				}
+				for _, current := range tagsAt(op.J1 - 1) {
+					if after[current.Placeholder()] {
+						add(current)
+					}
+				}
+			}
+		}
+
+		if len(found) > 0 {
+			sort.Strings(found)
+			hunks = append(hunks, fmt.Sprintf("@@ -%v +%v @@ %v\n", hunkRange(first.I1, last.I2), hunkRange(first.J1, last.J2), strings.Join(found, ", ")))
+		}
+	}
+
+	if len(hunks) == 0 {
+		return nil
+	}
+
+	_, err = fmt.Fprintf(writer, "Tags in the changed lines:\n%v\n", strings.Join(hunks, ""))
+	return err
+}
+
+func tagName(t tag.Tag) string {
+	return fmt.Sprintf("%v (%v:%v)", t.Placeholder(), t.File(), t.Line())
+}
+
+// hunkRange formats the lines of a hunk like the unified diff.
+func hunkRange(start, stop int) string {
+	beginning, length := start+1, stop-start
+	if length == 1 {
+		return strconv.Itoa(beginning)
+	}
+	if length == 0 {
+		beginning--
+	}
+	return fmt.Sprintf("%v,%v", beginning, length)
+}
+
+// init is run by Go on startup. https://tutorialedge.net/golang/the-go-init-function/
+func init() {
+	addGenerateFlags(checkCmd)
+	rootCmd.AddCommand(checkCmd)
+}
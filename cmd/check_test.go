@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/core"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/finder"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/generator"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_check(t *testing.T) {
+	tests := []struct {
+		name         string
+		template     string
+		readme       *string
+		wantOutdated int
+		wantDiff     []string
+		wantNoTags   bool
+	}{
+		{
+			name:         "up to date",
+			readme:       strPtr("# Readme\nHello  \nWorld\n\n"),
+			wantOutdated: 0,
+		},
+		{
+			name:         "windows line endings are up to date",
+			readme:       strPtr("# Readme\r\nHello  \r\nWorld\r\n\r\n"),
+			wantOutdated: 0,
+		},
+		{
+			name:         "outdated",
+			readme:       strPtr("# Readme\nGoodbye\nWorld\n\n"),
+			wantOutdated: 1,
+			wantDiff: []string{
+				"--- README.md\n",
+				"+++ README.md (generated from templates/README.tpl.md)\n",
+				"-Goodbye\n",
+				"+Hello  \n",
+				"Tags in the changed lines:\n@@ -1,5 +1,5 @@ hello (main.go:1)\n",
+			},
+		},
+		{
+			name:         "removed lines inside of a tag",
+			readme:       strPtr("# Readme\nHello  \nRemoved\nWorld\n\n"),
+			wantOutdated: 1,
+			wantDiff: []string{
+				"-Removed\n",
+				"Tags in the changed lines:\n@@ -1,6 +1,5 @@ hello (main.go:1)\n",
+			},
+		},
+		{
+			name:         "no tag in the changed lines",
+			readme:       strPtr("# Old Readme\nHello  \nWorld\n\nRemoved\n"),
+			wantOutdated: 1,
+			wantDiff: []string{
+				"-# Old Readme\n",
+				"-Removed\n",
+			},
+			wantNoTags: true,
+		},
+		{
+			name:         "the time of .Now is ignored",
+			template:     "# Readme\n{{ .Tag.hello }}\nUpdated {{ .Now }} ({{ .Now \"2006-01-02\" }})\n",
+			readme:       strPtr("# Readme\nHello  \nWorld\nUpdated 04 Mar 22 05:06 +0000 (2022-03-04)\n\n"),
+			wantOutdated: 0,
+		},
+		{
+			name:         "outdated with .Now",
+			template:     "# Readme\n{{ .Tag.hello }}\nUpdated {{ .Now }}\n",
+			readme:       strPtr("# Readme\nGoodbye\nUpdated 04 Mar 22 05:06 +0000\n\n"),
+			wantOutdated: 1,
+			wantDiff: []string{
+				"-Goodbye\n",
+				"+Hello  \n",
+				"-Updated 04 Mar 22 05:06 +0000\n",
+			},
+		},
+		{
+			name:         "missing",
+			readme:       nil,
+			wantOutdated: 1,
+			wantDiff: []string{
+				"+# Readme\n",
+				"+Hello  \n",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projectPath := t.TempDir()
+			assert.NoError(t, os.MkdirAll(filepath.Join(projectPath, "templates"), 0775))
+			assert.NoError(t, os.WriteFile(filepath.Join(projectPath, "main.go"), []byte("// @WHY hello\n// Hello\n// World\npackage main\n"), 0664))
+			template := tt.template
+			if template == "" {
+				template = "# Readme\n{{ .Tag.hello }}\n"
+			}
+			assert.NoError(t, os.WriteFile(filepath.Join(projectPath, "templates", "README.tpl.md"), []byte(template), 0664))
+			if tt.readme != nil {
+				assert.NoError(t, os.WriteFile(filepath.Join(projectPath, "README.md"), []byte(*tt.readme), 0664))
+			}
+
+			atwhy, err := core.New(generator.Markdown{}, projectPath, "/", "templates", nil, map[string]finder.CommentConfig{
+				".go": {LineComment: []string{"//"}},
+			}, core.Options{})
+			assert.NoError(t, err)
+
+			templates, err := atwhy.Load()
+			assert.NoError(t, err)
+
+			writer := &bytes.Buffer{}
+			outdated, err := check(&atwhy, templates, afero.NewBasePathFs(afero.NewOsFs(), projectPath), "templates", writer)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantOutdated, outdated)
+
+			if tt.wantOutdated == 0 {
+				assert.Empty(t, writer.String())
+			}
+			if tt.wantNoTags {
+				assert.NotContains(t, writer.String(), "Tags in the changed lines")
+			}
+			for _, diffLine := range tt.wantDiff {
+				assert.Contains(t, writer.String(), diffLine)
+			}
+		})
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
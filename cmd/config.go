@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/core"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/diagnostic"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/finder"
+	"gopkg.in/yaml.v2"
+)
+
+// configFile is the name of the config file which is loaded from the project root.
+const configFile = "atwhy.yaml"
+
+// @WHY readme_config
+// Instead of passing all flags each time, you can create an `atwhy.yaml` file
+// in the root of your project. It is loaded automatically.
+// (Use `--config path/to/config.yaml` to load it from another location.)
+// Flags passed on the command line always override the values of the file.
+//
+// Example with all possible fields:
+// ```yaml
+// # Same as --templates-folder
+// templates-folder: templates
+// # Same as --ext
+// ext: [.go, .js, .ts]
+// # Same as --generator
+// generator: md
+// # Same as --output
+// output: docs
+// # Same as --strict
+// strict: false
+// # Same as --duplicates
+// duplicates: warning
+// # Same as --missing
+// missing: error
+// # Same as --comment but structured.
+// # If at least one rule is set, the built-in rules are disabled
+// # unless you add a rule with "builtin: true".
+// comments:
+//   - builtin: true
+//   - ext: [sh, bash]
+//     line: ["#"]
+//   - ext: [html, xml]
+//     block:
+//       - start: "<!--"
+//         end: "-->"
+//   # A rule without ext catches all not otherwise configured extensions.
+//   - line: ["//"]
+//     block:
+//       - start: "/*"
+//         end: "*/"
+// serve:
+//   # The default host for atwhy serve.
+//   host: localhost:4444
+//   # Same as --base-path of atwhy serve.
+//   base-path: /
+//   # Same as --allow-dotfiles of atwhy serve.
+//   allow-dotfiles: false
+//   # Same as --only-referenced of atwhy serve.
+//   only-referenced: false
+// site:
+//   # The default output folder for atwhy site.
+//   output: site
+// # Same as --theme
+// theme: docs/theme
+// # Same as --cache
+// cache: false
+// # Same as --source-link
+// source-link: https://github.com/{repo}/blob/{commit}/{path}#L{line}
+// lint:
+//   # The severities of the rules of atwhy lint, same as --rule.
+//   rules:
+//     unused-tag: off
+//     empty-tag: error
+// ```
+
+// Config contains all options which can be set by the config file or the flags.
+type Config struct {
+	TemplatesFolder string        `yaml:"templates-folder"`
+	Extensions      []string      `yaml:"ext"`
+	Comments        []CommentRule `yaml:"comments"`
+	Generator       string        `yaml:"generator"`
+	Output          string        `yaml:"output"`
+	Strict          bool          `yaml:"strict"`
+
+	Duplicates diagnostic.Severity `yaml:"duplicates"`
+	Missing    diagnostic.Severity `yaml:"missing"`
+	Serve      ServeConfig         `yaml:"serve"`
+	Site       SiteConfig          `yaml:"site"`
+	Theme      string              `yaml:"theme"`
+	Cache      bool                `yaml:"cache"`
+	SourceLink string              `yaml:"source-link"`
+	Lint       LintConfig          `yaml:"lint"`
+
+	// Ref can only be set by the flag, as the config file is always read from the working tree.
+	Ref string `yaml:"-"`
+
+	// ProjectPath is the absolute path to the project.
+	ProjectPath string `yaml:"-"`
+
+	// CommentConfig is calculated from the Comments.
+	CommentConfig map[string]finder.CommentConfig `yaml:"-"`
+}
+
+// CoreOptions returns the options for core.New.
+func (c Config) CoreOptions() core.Options {
+	return core.Options{
+		DuplicateSeverity: c.Duplicates,
+		MissingSeverity:   c.Missing,
+		ThemeFolder:       c.Theme,
+		Cache:             c.Cache,
+		Ref:               c.Ref,
+		SourceLink:        c.SourceLink,
+	}
+}
+
+type ServeConfig struct {
+	Host           string `yaml:"host"`
+	BasePath       string `yaml:"base-path"`
+	AllowDotfiles  bool   `yaml:"allow-dotfiles"`
+	OnlyReferenced bool   `yaml:"only-referenced"`
+}
+
+type SiteConfig struct {
+	Output string `yaml:"output"`
+}
+
+type LintConfig struct {
+	// Rules maps the codes of the rules to their severity ('error', 'warning' or 'off').
+	Rules map[string]diagnostic.Severity `yaml:"rules"`
+}
+
+// CommentRule is the structured version of a --comment string.
+type CommentRule struct {
+	// Builtin adds all built-in rules.
+	// If it is set, all other fields are ignored.
+	Builtin bool `yaml:"builtin"`
+
+	// Ext is the list of file extensions the rule applies to.
+	// If it is empty, the rule applies to all not otherwise configured extensions.
+	Ext   []string       `yaml:"ext"`
+	Line  []string       `yaml:"line"`
+	Block []BlockComment `yaml:"block"`
+}
+
+type BlockComment struct {
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
+}
+
+// loadConfigFile reads the given config file.
+// If the file does not exist and it is not required, an empty Config is returned.
+func loadConfigFile(path string, required bool) (Config, error) {
+	config := Config{}
+
+	data, err := os.ReadFile(path)
+	if !required && errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return Config{}, err
+	}
+
+	// Use strict mode to report typos in the keys.
+	err = yaml.UnmarshalStrict(data, &config)
+	if err != nil {
+		return Config{}, fmt.Errorf("%s: %w", filepath.ToSlash(path), err)
+	}
+
+	return config, nil
+}
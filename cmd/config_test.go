@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/diagnostic"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/finder"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+const testConfig = `templates-folder: docs/templates
+ext: [.go]
+generator: html
+output: public
+comments:
+  - ext: [sh, .bash]
+    line: ["#"]
+  - line: ["//"]
+    block:
+      - start: "/*"
+        end: "*/"
+serve:
+  host: :8080
+`
+
+func testCommand(t *testing.T, projectPath string, args ...string) *cobra.Command {
+	cmd := &cobra.Command{Use: "test"}
+	addCommonFlags(cmd)
+	addGenerateFlags(cmd)
+	assert.NoError(t, cmd.ParseFlags(append([]string{"--project", projectPath}, args...)))
+	return cmd
+}
+
+func TestLoadCommonArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		args    []string
+		want    Config
+		wantErr assert.ErrorAssertionFunc
+	}{
+		{
+			name:   "no config file uses the defaults",
+			config: "",
+			want: Config{
+				TemplatesFolder: "templates",
+				Extensions:      []string{},
+				Generator:       "md",
+				Duplicates:      diagnostic.SeverityWarning,
+				Missing:         diagnostic.SeverityError,
+				Comments:        []CommentRule{{Builtin: true}},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "values from the config file",
+			config: testConfig,
+			want: Config{
+				TemplatesFolder: "docs/templates",
+				Extensions:      []string{".go"},
+				Generator:       "html",
+				Output:          "public",
+				Duplicates:      diagnostic.SeverityWarning,
+				Missing:         diagnostic.SeverityError,
+				Comments: []CommentRule{
+					{Ext: []string{"sh", ".bash"}, Line: []string{"#"}},
+					{Line: []string{"//"}, Block: []BlockComment{{Start: "/*", End: "*/"}}},
+				},
+				Serve: ServeConfig{Host: ":8080"},
+				CommentConfig: map[string]finder.CommentConfig{
+					".sh":   {LineComment: []string{"#"}},
+					".bash": {LineComment: []string{"#"}},
+					".":     {LineComment: []string{"//"}, BlockStart: []string{"/*"}, BlockEnd: []string{"*/"}},
+				},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name:   "flags override the config file",
+			config: testConfig,
+			args:   []string{"-t", "tpl", "--ext", ".js", "-g", "md", "-o", "out", "--comment", "lua:--"},
+			want: Config{
+				TemplatesFolder: "tpl",
+				Extensions:      []string{".js"},
+				Generator:       "md",
+				Output:          "out",
+				Duplicates:      diagnostic.SeverityWarning,
+				Missing:         diagnostic.SeverityError,
+				Comments: []CommentRule{
+					{Ext: []string{"lua"}, Line: []string{"--"}},
+				},
+				Serve: ServeConfig{Host: ":8080"},
+				CommentConfig: map[string]finder.CommentConfig{
+					".lua": {LineComment: []string{"--"}},
+				},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name:    "unknown keys are reported",
+			config:  "template-folder: docs",
+			wantErr: assert.Error,
+		},
+		{
+			name: "invalid block rule",
+			config: `comments:
+  - block:
+      - start: "/*"`,
+			wantErr: func(tt assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(tt, err, ErrInvalidCommentRule)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projectPath := t.TempDir()
+			if tt.config != "" {
+				assert.NoError(t, os.WriteFile(filepath.Join(projectPath, configFile), []byte(tt.config), 0664))
+			}
+
+			got, err := LoadCommonArgs(testCommand(t, projectPath, tt.args...))
+			if !tt.wantErr(t, err) || err != nil {
+				return
+			}
+
+			tt.want.ProjectPath = projectPath
+			if tt.want.CommentConfig == nil {
+				// Only check that the builtin rules were loaded.
+				assert.Contains(t, got.CommentConfig, ".sh")
+				tt.want.CommentConfig = got.CommentConfig
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("duplicates severity", func(t *testing.T) {
+		projectPath := t.TempDir()
+		assert.NoError(t, os.WriteFile(filepath.Join(projectPath, configFile), []byte("duplicates: error"), 0664))
+
+		got, err := LoadCommonArgs(testCommand(t, projectPath))
+		assert.NoError(t, err)
+		assert.Equal(t, diagnostic.SeverityError, got.Duplicates)
+
+		got, err = LoadCommonArgs(testCommand(t, projectPath, "--duplicates", "warning"))
+		assert.NoError(t, err)
+		assert.Equal(t, diagnostic.SeverityWarning, got.Duplicates)
+
+		_, err = LoadCommonArgs(testCommand(t, projectPath, "--duplicates", "info"))
+		assert.ErrorIs(t, err, diagnostic.ErrUnknownSeverity)
+	})
+
+	t.Run("explicit config file has to exist", func(t *testing.T) {
+		projectPath := t.TempDir()
+		_, err := LoadCommonArgs(testCommand(t, projectPath, "--config", filepath.Join(projectPath, "missing.yaml")))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/core"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/diagnostic"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/generator"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/cobra"
+)
+
+var ErrTagsChanged = errors.New("some tags have changed")
+
+// workingTree is the name used for the working tree in the output of the diff.
+const workingTree = "working tree"
+
+// @WHY readme_usage6_diff
+//
+// __Diff__
+// To see which tags have changed between two git revisions, run:
+// ```bash
+// atwhy diff [FROM (default: HEAD)] [TO (default: the working tree)]
+// ```
+// e.g. `atwhy diff main` in a feature branch or `atwhy diff v1.0.0 v2.0.0`.
+// It prints each added, removed or changed placeholder with a diff of its value
+// and the templates using it. Pass `--exit-code` to exit with a non-zero exit code if anything has changed.
+
+// diffCmd compares the tags of two revisions.
+var diffCmd = &cobra.Command{
+	Use:   "diff [FROM (e.g. HEAD)] [TO]",
+	Short: "Shows the changed tags between two git revisions.",
+	Long: `Shows the changed tags between two git revisions.
+FROM and TO can be any git commit, tag or branch.
+FROM defaults to HEAD and TO to the working tree.
+
+For each added, removed or changed placeholder it prints a diff of the value
+and the templates which use it.`,
+	Args: cobra.MaximumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := LoadCommonArgs(cmd)
+		if err != nil {
+			return err
+		}
+
+		from := "HEAD"
+		if len(args) > 0 {
+			from = args[0]
+		}
+		to := ""
+		if len(args) > 1 {
+			to = args[1]
+		}
+
+		fromSnapshot, err := loadSnapshot(config, from)
+		if err != nil {
+			return fmt.Errorf("%v: %w", from, err)
+		}
+		toSnapshot, err := loadSnapshot(config, to)
+		if err != nil {
+			return fmt.Errorf("%v: %w", revisionName(to), err)
+		}
+
+		changes := core.DiffTags(fromSnapshot, toSnapshot)
+		err = printTagChanges(cmd.OutOrStdout(), changes, from, revisionName(to))
+		if err != nil {
+			return err
+		}
+
+		exitCode, err := cmd.Flags().GetBool("exit-code")
+		if err != nil {
+			return err
+		}
+		if exitCode && len(changes) > 0 {
+			return ErrTagsChanged
+		}
+		return nil
+	},
+}
+
+// revisionName returns the name of the revision used in the output.
+func revisionName(ref string) string {
+	if ref == "" {
+		return workingTree
+	}
+	return ref
+}
+
+// loadSnapshot loads the tags and templates of the given ref.
+// An empty ref loads the working tree.
+func loadSnapshot(config Config, ref string) (core.Snapshot, error) {
+	atwhy, err := newSnapshotAtWhy(config, ref)
+	if err != nil {
+		return core.Snapshot{}, err
+	}
+
+	return atwhy.Snapshot()
+}
+
+// newSnapshotAtWhy creates the AtWhy to load the tags and templates of the given ref.
+// Duplicate and missing tags are only reported as warnings.
+func newSnapshotAtWhy(config Config, ref string) (core.AtWhy, error) {
+	options := config.CoreOptions()
+	options.Ref = ref
+	// The links are not part of the values of the tags.
+	options.SourceLink = ""
+
+	// Only the values of the tags are compared, so problems of the templates must not stop the diff.
+	options.DuplicateSeverity = diagnostic.SeverityWarning
+	options.MissingSeverity = diagnostic.SeverityWarning
+
+	return core.New(generator.Markdown{}, config.ProjectPath, "/", config.TemplatesFolder, config.Extensions, config.CommentConfig, options)
+}
+
+// printTagChanges writes a unified diff of each change.
+func printTagChanges(writer io.Writer, changes []core.TagChange, from string, to string) error {
+	for _, change := range changes {
+		templates := "no template"
+		if len(change.Templates) > 0 {
+			templates = strings.Join(change.Templates, ", ")
+		}
+
+		_, err := fmt.Fprintf(writer, "%v %v (used by %v)\n", change.Kind, change.Placeholder, templates)
+		if err != nil {
+			return err
+		}
+
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        diffLines(change.Old),
+			B:        diffLines(change.New),
+			FromFile: change.Placeholder + " (" + from + ")",
+			ToFile:   change.Placeholder + " (" + to + ")",
+			Context:  3,
+		})
+		if err != nil {
+			return err
+		}
+
+		if _, err := fmt.Fprintln(writer, diff); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// diffLines splits the value into lines.
+// Empty values have no lines at all, so that added and removed tags show only their value.
+func diffLines(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return difflib.SplitLines(strings.TrimSuffix(value, "\n"))
+}
+
+// init is run by Go on startup. https://tutorialedge.net/golang/the-go-init-function/
+func init() {
+	diffCmd.Flags().Bool("exit-code", false, "exit with a non-zero exit code if any tag has changed")
+	rootCmd.AddCommand(diffCmd)
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/core"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/finder"
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_diff(t *testing.T) {
+	projectPath := t.TempDir()
+	repo, err := git.PlainInit(projectPath, false)
+	assert.NoError(t, err)
+	worktree, err := repo.Worktree()
+	assert.NoError(t, err)
+
+	writeFile := func(name string, content string) {
+		assert.NoError(t, os.MkdirAll(filepath.Join(projectPath, filepath.Dir(name)), 0775))
+		assert.NoError(t, os.WriteFile(filepath.Join(projectPath, name), []byte(content), 0664))
+	}
+
+	writeFile("main.go", "// @WHY hello\n// Hello\n// World\npackage main\n")
+	writeFile("templates/README.tpl.md", "# Readme\n{{ .Tag.hello }}\n")
+	assert.NoError(t, worktree.AddGlob("."))
+	_, err = worktree.Commit("initial", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	assert.NoError(t, err)
+
+	writeFile("main.go", "// @WHY hello\n// Hello\n// Moon\npackage main\n")
+
+	config := Config{
+		TemplatesFolder: "templates",
+		ProjectPath:     projectPath,
+		CommentConfig: map[string]finder.CommentConfig{
+			".go": {LineComment: []string{"//"}},
+		},
+	}
+
+	from, err := loadSnapshot(config, "HEAD")
+	assert.NoError(t, err)
+	to, err := loadSnapshot(config, "")
+	assert.NoError(t, err)
+
+	changes := core.DiffTags(from, to)
+	writer := &bytes.Buffer{}
+	assert.NoError(t, printTagChanges(writer, changes, "HEAD", revisionName("")))
+	assert.Equal(t, `changed hello (used by templates/README.tpl.md)
+--- hello (HEAD)
++++ hello (working tree)
+@@ -1,2 +1,2 @@
+ Hello  
+-World
++Moon
+
+`, writer.String())
+
+	changes = core.DiffTags(from, from)
+	assert.Empty(t, changes)
+}
@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/core"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/diagnostic"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/generator"
+	"github.com/spf13/cobra"
+)
+
+var ErrLintFailed = errors.New("the linter found errors")
+
+var ErrUnknownLintFormat = errors.New("unknown format, possible values are: 'text', 'json', 'github'")
+var ErrUnknownRule = errors.New("unknown lint rule (see atwhy lint --help)")
+var ErrInvalidRuleString = errors.New("a rule has to be like '{rule}={severity}', e.g. 'unused-tag=off'")
+
+// @WHY readme_usage8_lint
+//
+// __Lint__
+// To check the tags and templates for common problems, run:
+// ```bash
+// atwhy lint
+// ```
+// It reports e.g. invalid, duplicate, unused, missing and empty tags, unclosed CODE blocks,
+// unknown keys in the template headers and `.Project` links to files which do not exist.
+// It exits with a non-zero exit code if there is any error (or any warning with `--strict`).
+//
+// Each rule can be set to `error`, `warning` or `off`, e.g. `--rule unused-tag=off --rule empty-tag=error`
+// or in the `lint.rules` of the config file. Run `atwhy lint --help` for all rules.
+// Pass `--format json` for other tools or `--format github` for annotations in GitHub Actions.
+
+// lintCmd checks the tags and templates.
+var lintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Checks the tags and templates for problems.",
+	Long: `Checks the tags and templates for problems.
+It prints all problems and exits with a non-zero exit code if there is any error
+(or any warning in strict mode).
+
+The severity of each rule can be changed with --rule or the lint.rules of the config file.
+The default severities of duplicate-placeholder and missing-tag are the ones of --duplicates and --missing.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := LoadCommonArgs(cmd)
+		if err != nil {
+			return err
+		}
+
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return err
+		}
+
+		flagRules, err := cmd.Flags().GetStringArray("rule")
+		if err != nil {
+			return err
+		}
+		rules, err := lintRules(config, flagRules)
+		if err != nil {
+			return err
+		}
+
+		diagnostics, err := lint(config)
+		if err != nil {
+			return err
+		}
+		diagnostics = rules.Apply(diagnostics)
+
+		err = printLint(cmd.OutOrStdout(), diagnostics, format)
+		if err != nil {
+			return err
+		}
+
+		for _, d := range diagnostics {
+			if d.Severity == diagnostic.SeverityError {
+				return ErrLintFailed
+			}
+		}
+		if config.Strict && len(diagnostics) > 0 {
+			return ErrStrict
+		}
+		return nil
+	},
+}
+
+// lint loads the project and returns all diagnostics with their default severities.
+func lint(config Config) ([]diagnostic.Diagnostic, error) {
+	options := config.CoreOptions()
+	// The links are not checked.
+	options.SourceLink = ""
+
+	// The severities are applied by the rules, so nothing must stop the loading.
+	options.DuplicateSeverity = diagnostic.SeverityWarning
+	options.MissingSeverity = diagnostic.SeverityWarning
+
+	atwhy, err := core.New(generator.Markdown{}, config.ProjectPath, "/", config.TemplatesFolder, config.Extensions, config.CommentConfig, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return atwhy.Lint()
+}
+
+// lintRules combines the severities of --duplicates and --missing with the rules of the config and the flags.
+// The flags are like "unused-tag=off" and override the config.
+func lintRules(config Config, flagRules []string) (diagnostic.Rules, error) {
+	rules := diagnostic.Rules{}
+	if config.Duplicates != "" {
+		rules["duplicate-placeholder"] = config.Duplicates
+	}
+	if config.Missing != "" {
+		rules["missing-tag"] = config.Missing
+	}
+
+	add := func(code string, severity string) error {
+		known := false
+		for _, rule := range core.LintRules {
+			if rule.Code == code {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("%v: %w", code, ErrUnknownRule)
+		}
+
+		parsed, err := diagnostic.ParseRuleSeverity(severity)
+		if err != nil {
+			return fmt.Errorf("%v: %w", code, err)
+		}
+		rules[code] = parsed
+		return nil
+	}
+
+	for code, severity := range config.Lint.Rules {
+		if err := add(code, string(severity)); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, rule := range flagRules {
+		splitted := strings.SplitN(rule, "=", 2)
+		if len(splitted) != 2 {
+			return nil, fmt.Errorf("%v: %w", rule, ErrInvalidRuleString)
+		}
+		if err := add(strings.TrimSpace(splitted[0]), strings.TrimSpace(splitted[1])); err != nil {
+			return nil, err
+		}
+	}
+
+	return rules, nil
+}
+
+// printLint writes the diagnostics in the given format.
+func printLint(writer io.Writer, diagnostics []diagnostic.Diagnostic, format string) error {
+	switch format {
+	case "text":
+		for _, d := range diagnostics {
+			_, err := fmt.Fprintf(writer, "%v [%v]\n", d, d.Code)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case "json":
+		if diagnostics == nil {
+			diagnostics = []diagnostic.Diagnostic{}
+		}
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(diagnostics)
+	case "github":
+		for _, d := range diagnostics {
+			_, err := fmt.Fprintln(writer, githubAnnotation(d))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return ErrUnknownLintFormat
+}
+
+// githubAnnotation formats the Diagnostic as workflow command of GitHub Actions, e.g.
+//
+//	::warning file=main.go,line=3,col=4,title=atwhy empty-tag::the \@WHY example has no content
+//
+// See https://docs.github.com/en/actions/using-workflows/workflow-commands-for-github-actions
+func githubAnnotation(d diagnostic.Diagnostic) string {
+	properties := "file=" + escapeGithubProperty(d.File)
+	if d.Line > 0 {
+		properties += ",line=" + strconv.Itoa(d.Line)
+		if d.Column > 0 {
+			properties += ",col=" + strconv.Itoa(d.Column)
+		}
+	}
+	properties += ",title=" + escapeGithubProperty("atwhy "+d.Code)
+
+	return "::" + string(d.Severity) + " " + properties + "::" + escapeGithubData(d.Message)
+}
+
+func escapeGithubData(value string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(value)
+}
+
+func escapeGithubProperty(value string) string {
+	return strings.NewReplacer(":", "%3A", ",", "%2C").Replace(escapeGithubData(value))
+}
+
+// init is run by Go on startup. https://tutorialedge.net/golang/the-go-init-function/
+func init() {
+	lintCmd.Long += "\n\nRules:"
+	for _, rule := range core.LintRules {
+		lintCmd.Long += "\n  " + rule.Code + ": " + rule.Description
+	}
+
+	lintCmd.Flags().StringP("format", "f", "text", "the output format\npossible values are: 'text', 'json', 'github'")
+	lintCmd.Flags().StringArray("rule", nil, "set the severity of a rule, e.g. 'unused-tag=off'\npossible severities are: 'error', 'warning', 'off'\ncan be passed multiple times")
+	rootCmd.AddCommand(lintCmd)
+}
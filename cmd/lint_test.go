@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/diagnostic"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_lintRules(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    Config
+		flagRules []string
+		want      diagnostic.Rules
+		wantErr   error
+	}{
+		{
+			name:   "defaults of duplicates and missing",
+			config: Config{Duplicates: diagnostic.SeverityWarning, Missing: diagnostic.SeverityError},
+			want: diagnostic.Rules{
+				"duplicate-placeholder": diagnostic.SeverityWarning,
+				"missing-tag":           diagnostic.SeverityError,
+			},
+		},
+		{
+			name: "flags override the config",
+			config: Config{
+				Missing: diagnostic.SeverityError,
+				Lint: LintConfig{Rules: map[string]diagnostic.Severity{
+					"missing-tag": diagnostic.SeverityWarning,
+					"unused-tag":  diagnostic.SeverityOff,
+				}},
+			},
+			flagRules: []string{"unused-tag=error", "empty-tag = off"},
+			want: diagnostic.Rules{
+				"missing-tag": diagnostic.SeverityWarning,
+				"unused-tag":  diagnostic.SeverityError,
+				"empty-tag":   diagnostic.SeverityOff,
+			},
+		},
+		{
+			name:      "unknown rule",
+			flagRules: []string{"unsued-tag=off"},
+			wantErr:   ErrUnknownRule,
+		},
+		{
+			name:      "unknown severity",
+			flagRules: []string{"unused-tag=info"},
+			wantErr:   diagnostic.ErrUnknownRuleSeverity,
+		},
+		{
+			name:      "invalid rule string",
+			flagRules: []string{"unused-tag"},
+			wantErr:   ErrInvalidRuleString,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := lintRules(tt.config, tt.flagRules)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_lintRules_configFile(t *testing.T) {
+	projectPath := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(projectPath, configFile), []byte("lint:\n  rules:\n    unused-tag: off\n    empty-tag: error\n"), 0664))
+
+	config, err := LoadCommonArgs(testCommand(t, projectPath))
+	assert.NoError(t, err)
+
+	rules, err := lintRules(config, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, diagnostic.SeverityOff, rules["unused-tag"])
+	assert.Equal(t, diagnostic.SeverityError, rules["empty-tag"])
+}
+
+func Test_printLint(t *testing.T) {
+	diagnostics := []diagnostic.Diagnostic{
+		{Severity: diagnostic.SeverityWarning, Code: "empty-tag", File: "main.go", Line: 3, Column: 4, Message: "the @WHY example has no content"},
+		{Severity: diagnostic.SeverityError, Code: "invalid-project-link", File: "templates/README.tpl.md", Message: "100% broken,\nreally"},
+	}
+
+	tests := []struct {
+		name        string
+		format      string
+		diagnostics []diagnostic.Diagnostic
+		want        string
+		wantErr     error
+	}{
+		{
+			name:        "text",
+			format:      "text",
+			diagnostics: diagnostics,
+			want: "main.go:3:4: warning: the @WHY example has no content [empty-tag]\n" +
+				"templates/README.tpl.md: error: 100% broken,\nreally [invalid-project-link]\n",
+		},
+		{
+			name:        "json",
+			format:      "json",
+			diagnostics: diagnostics[:1],
+			want: `[
+  {
+    "severity": "warning",
+    "code": "empty-tag",
+    "file": "main.go",
+    "line": 3,
+    "column": 4,
+    "message": "the @WHY example has no content"
+  }
+]
+`,
+		},
+		{
+			name:   "json without diagnostics",
+			format: "json",
+			want:   "[]\n",
+		},
+		{
+			name:        "github",
+			format:      "github",
+			diagnostics: diagnostics,
+			want: "::warning file=main.go,line=3,col=4,title=atwhy empty-tag::the @WHY example has no content\n" +
+				"::error file=templates/README.tpl.md,title=atwhy invalid-project-link::100%25 broken,%0Areally\n",
+		},
+		{
+			name:    "unknown format",
+			format:  "xml",
+			wantErr: ErrUnknownLintFormat,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := &bytes.Buffer{}
+			err := printLint(writer, tt.diagnostics, tt.format)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, writer.String())
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/core"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/diagnostic"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/generator"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/lsp"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+// @WHY readme_usage9_lsp
+//
+// __Editor support__
+// atwhy includes a language server which can be used by any editor supporting the
+// [Language Server Protocol](https://microsoft.github.io/language-server-protocol/).
+// Configure your editor to start it with:
+// ```bash
+// atwhy lsp
+// ```
+// It completes placeholders in the templates (after `{{"{{ .Tag."}}`), jumps from a reference to the `\@WHY` of the tag,
+// finds all templates using a tag and shows the content of tags on hover.
+// Problems like invalid or duplicate tags are shown as diagnostics with the rules of `atwhy lint`.
+// Unsaved changes of open files are used as well.
+
+// lspCmd starts the language server.
+var lspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "Starts a language server for editors.",
+	Long: `Starts a language server which communicates over stdin and stdout.
+It supports completion, go to definition, find references and hover
+for the placeholders in the templates and the tags in the project files.
+Problems like invalid or duplicate tags are published as diagnostics with the rules of atwhy lint.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := LoadCommonArgs(cmd)
+		if err != nil {
+			return err
+		}
+
+		rules, err := lintRules(config, nil)
+		if err != nil {
+			return err
+		}
+
+		server := lsp.Server{
+			ProjectPath:     config.ProjectPath,
+			TemplatesFolder: config.TemplatesFolder,
+			New: func(overlay afero.Fs) (core.AtWhy, error) {
+				options := config.CoreOptions()
+				// The editor always shows the working tree.
+				options.Ref = ""
+				options.SourceLink = ""
+				options.Overlay = overlay
+
+				// The severities are applied by the rules, so nothing must stop the loading.
+				options.DuplicateSeverity = diagnostic.SeverityWarning
+				options.MissingSeverity = diagnostic.SeverityWarning
+
+				return core.New(generator.Markdown{}, config.ProjectPath, "/", config.TemplatesFolder, config.Extensions, config.CommentConfig, options)
+			},
+			Rules: rules,
+			Log:   cmd.ErrOrStderr(),
+		}
+
+		return server.Serve(cmd.InOrStdin(), cmd.OutOrStdout())
+	},
+}
+
+// init is run by Go on startup. https://tutorialedge.net/golang/the-go-init-function/
+func init() {
+	rootCmd.AddCommand(lspCmd)
+}
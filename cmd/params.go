@@ -6,7 +6,11 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/core"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/diagnostic"
 	"github.com/Tiffinger-Thiel-GmbH/atwhy/finder"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/generator"
+	mdTemplate "github.com/Tiffinger-Thiel-GmbH/atwhy/template"
 	"github.com/spf13/cobra"
 )
 
@@ -25,66 +29,270 @@ var defaultComments = []string{
 	// @WHY CODE_END
 }
 
+var ErrStrict = errors.New("warnings are treated as errors because of the strict mode")
+
+var ErrUnknownGenerator = errors.New("unknown generator, possible values are: 'md', 'html'")
+
 var ErrInvalidCommentString = errors.New("comment configuration has to be like '{extList}:{lineComment}[,{blockStart},{blockEnd}]' (see --help)")
 var ErrInvalidCommentStringMissingBlock = fmt.Errorf("either blockStart or blockEnd is missing - %w", ErrInvalidCommentString)
+var ErrInvalidCommentRule = errors.New("each block comment rule needs a start and an end")
 
 // LoadCommonArgs loads everything which is common through the different modes.
-func LoadCommonArgs(cmd *cobra.Command) (templatesFolder string, projectPath string, extensions []string, commentConfig map[string]finder.CommentConfig, err error) {
-	templatesFolder, err = cmd.Flags().GetString("templates-folder")
+// The values are read from the config file and overridden by the flags if they are set.
+func LoadCommonArgs(cmd *cobra.Command) (Config, error) {
+	projectPath, err := cmd.Flags().GetString("project")
 	if err != nil {
-		return "", "", nil, nil, err
+		return Config{}, err
 	}
 
-	projectPath, err = cmd.Flags().GetString("project")
+	// Make the path absolute.
+	projectPath, err = filepath.Abs(projectPath)
 	if err != nil {
-		return "", "", nil, nil, err
+		return Config{}, err
 	}
 
-	// Make the path absolute.
-	projectPath, err = filepath.Abs(projectPath)
+	configPath, err := cmd.Flags().GetString("config")
 	if err != nil {
-		return "", "", nil, nil, err
+		return Config{}, err
 	}
 
-	extensions, err = cmd.Flags().GetStringSlice("ext")
+	var config Config
+	if configPath != "" {
+		config, err = loadConfigFile(configPath, true)
+	} else {
+		config, err = loadConfigFile(filepath.Join(projectPath, configFile), false)
+	}
 	if err != nil {
-		return "", "", nil, nil, err
+		return Config{}, err
 	}
 
-	comments, err := cmd.Flags().GetStringArray("comment")
+	config.ProjectPath = projectPath
+
+	config.TemplatesFolder, err = stringOption(cmd, "templates-folder", config.TemplatesFolder)
 	if err != nil {
-		return "", "", nil, nil, err
+		return Config{}, err
 	}
 
-	if len(comments) == 0 {
-		comments = append(comments, "DEFAULT")
+	config.Extensions, err = stringSliceOption(cmd, "ext", config.Extensions)
+	if err != nil {
+		return Config{}, err
 	}
 
-	// Replace DEFAULT with the default comments.
-	extendedComments := make([]string, 0, len(comments))
-	for i, comment := range comments {
-		if comment == "DEFAULT" {
-			preComments := comments[:i]
-			extendedComments = append(preComments, defaultComments...)
-		} else {
-			extendedComments = append(extendedComments, comment)
+	config.Generator, err = stringOption(cmd, "generator", config.Generator)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.Output, err = stringOption(cmd, "output", config.Output)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.Serve.BasePath, err = stringOption(cmd, "base-path", config.Serve.BasePath)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.Serve.AllowDotfiles, err = boolOption(cmd, "allow-dotfiles", config.Serve.AllowDotfiles)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.Serve.OnlyReferenced, err = boolOption(cmd, "only-referenced", config.Serve.OnlyReferenced)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.Theme, err = stringOption(cmd, "theme", config.Theme)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.Strict, err = boolOption(cmd, "strict", config.Strict)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.Cache, err = boolOption(cmd, "cache", config.Cache)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.Ref, err = stringOption(cmd, "ref", config.Ref)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config.SourceLink, err = stringOption(cmd, "source-link", config.SourceLink)
+	if err != nil {
+		return Config{}, err
+	}
+
+	duplicates, err := stringOption(cmd, "duplicates", string(config.Duplicates))
+	if err != nil {
+		return Config{}, err
+	}
+	config.Duplicates, err = diagnostic.ParseSeverity(duplicates)
+	if err != nil {
+		return Config{}, fmt.Errorf("duplicates: %w", err)
+	}
+
+	missing, err := stringOption(cmd, "missing", string(config.Missing))
+	if err != nil {
+		return Config{}, err
+	}
+	config.Missing, err = diagnostic.ParseSeverity(missing)
+	if err != nil {
+		return Config{}, fmt.Errorf("missing: %w", err)
+	}
+
+	// The comments of the flags replace the comment rules of the config file completely.
+	comments, err := cmd.Flags().GetStringArray("comment")
+	if err != nil {
+		return Config{}, err
+	}
+
+	if len(comments) > 0 {
+		config.Comments, err = parseCommentRules(comments)
+		if err != nil {
+			return Config{}, err
 		}
 	}
 
-	comments = extendedComments
+	if len(config.Comments) == 0 {
+		config.Comments = []CommentRule{{Builtin: true}}
+	}
 
-	commentConfig, err = generateCommentConfig(comments)
+	config.CommentConfig, err = commentConfigFromRules(config.Comments)
 	if err != nil {
-		return "", "", nil, nil, err
+		return Config{}, err
 	}
 
-	return templatesFolder, projectPath, extensions, commentConfig, nil
+	return config, nil
+}
+
+// stringOption returns the value of the flag if it was set explicitly.
+// Otherwise, it returns the configValue, or the default of the flag if the configValue is empty.
+// If the command has no such flag, the configValue is returned.
+func stringOption(cmd *cobra.Command, name string, configValue string) (string, error) {
+	flag := cmd.Flags().Lookup(name)
+	if flag == nil || (!flag.Changed && configValue != "") {
+		return configValue, nil
+	}
+
+	return cmd.Flags().GetString(name)
+}
+
+// stringSliceOption works the same as stringOption but for string slices.
+func stringSliceOption(cmd *cobra.Command, name string, configValue []string) ([]string, error) {
+	flag := cmd.Flags().Lookup(name)
+	if flag == nil || (!flag.Changed && len(configValue) > 0) {
+		return configValue, nil
+	}
+
+	return cmd.Flags().GetStringSlice(name)
+}
+
+// boolOption works the same as stringOption but for booleans.
+// A true value in the config can be overridden by explicitly passing false to the flag.
+func boolOption(cmd *cobra.Command, name string, configValue bool) (bool, error) {
+	flag := cmd.Flags().Lookup(name)
+	if flag == nil || !flag.Changed {
+		return configValue, nil
+	}
+
+	return cmd.Flags().GetBool(name)
+}
+
+// @WHY readme_usage5_ref
+//
+// __Git revisions__
+// Pass `--ref` with any git commit, tag or branch to generate the documentation of that revision
+// without checking it out, e.g. `atwhy --ref v1.0.0 --output docs/v1.0.0`.
+// The project files and templates are read directly from the local `.git` folder.
+// The config file and the output folder are still the ones of the working tree.
+
+// @WHY readme_usage3_strict
+//
+// __Warnings__
+// Problems like invalid or unclosed tags are printed in the format
+// `file:line:column: warning: message`.
+// Pass `--strict` to exit with a non-zero exit code if there is any warning, e.g. in a CI.
+
+// load all templates and print the diagnostics.
+// In strict mode it returns ErrStrict if there is any warning.
+func load(cmd *cobra.Command, atwhy *core.AtWhy, strict bool) ([]mdTemplate.Markdown, error) {
+	templates, err := atwhy.Load()
+	err = printDiagnostics(cmd, atwhy, strict, err)
+	if err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// printDiagnostics prints all diagnostics collected by atwhy.
+// It returns the given err or, in strict mode, ErrStrict if there is any warning.
+func printDiagnostics(cmd *cobra.Command, atwhy *core.AtWhy, strict bool, err error) error {
+	for _, d := range atwhy.Diagnostics.Diagnostics() {
+		cmd.PrintErrln(d)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if strict && atwhy.Diagnostics.Count(diagnostic.SeverityWarning) > 0 {
+		return ErrStrict
+	}
+	return nil
+}
+
+// addGenerateFlags adds the flags needed to generate the files.
+func addGenerateFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("generator", "g", "md", "the generator to use\npossible values are: 'md', 'html'")
+	cmd.Flags().StringP("output", "o", "", "path to a folder where the generated files are written to relative to the project directory\ndefault is the project directory itself")
+	cmd.Flags().String("source-link", "", "url pattern for permalinks to the project files, e.g. 'https://github.com/{repo}/blob/{commit}/{path}#L{line}'\ndefault is a link relative to the generated files")
+}
+
+// addHTMLFlags adds the flags needed for the html pages.
+func addHTMLFlags(cmd *cobra.Command) {
+	cmd.Flags().String("theme", "", "path to a folder with a theme for the html pages relative to the project directory\ndefault is the built-in theme")
+}
+
+// NewGenerator creates the generator with the given name.
+func NewGenerator(generatorType string) (core.Generator, error) {
+	switch generatorType {
+	case "md":
+		return generator.Markdown{}, nil
+	case "html":
+		return &generator.HTML{
+			Markdown: generator.Markdown{},
+		}, nil
+	}
+
+	return nil, ErrUnknownGenerator
 }
 
 func generateCommentConfig(comments []string) (map[string]finder.CommentConfig, error) {
-	commentConfig := make(map[string]finder.CommentConfig)
+	rules, err := parseCommentRules(comments)
+	if err != nil {
+		return nil, err
+	}
+
+	return commentConfigFromRules(rules)
+}
+
+// parseCommentRules converts the --comment strings to CommentRules.
+// "DEFAULT" is converted to a builtin rule.
+func parseCommentRules(comments []string) ([]CommentRule, error) {
+	rules := make([]CommentRule, 0, len(comments))
 
 	for _, comment := range comments {
+		if comment == "DEFAULT" {
+			rules = append(rules, CommentRule{Builtin: true})
+			continue
+		}
+
 		split := strings.SplitN(comment, ":", 2)
 		if len(split) != 2 {
 			return nil, ErrInvalidCommentString
@@ -93,40 +301,77 @@ func generateCommentConfig(comments []string) (map[string]finder.CommentConfig,
 		commaPlaceholder := string([]byte{1})
 
 		cfgSplit := strings.Split(strings.ReplaceAll(split[1], `\,`, commaPlaceholder), ",")
+		cfgSplit = replaceInSlice(cfgSplit, commaPlaceholder, ",")
 
-		commentExtensions := strings.Split(split[0], ",")
-		for _, ext := range commentExtensions {
-			ext = "." + ext
+		rule := CommentRule{
+			Ext: strings.Split(split[0], ","),
+		}
 
-			if _, ok := commentConfig[ext]; !ok {
-				commentConfig[ext] = finder.CommentConfig{}
+		if len(cfgSplit) >= 1 && cfgSplit[0] != "" {
+			rule.Line = []string{cfgSplit[0]}
+		}
+
+		if len(cfgSplit) >= 3 {
+			if cfgSplit[1] == "" || cfgSplit[2] == "" {
+				return nil, ErrInvalidCommentStringMissingBlock
 			}
 
-			cfg := commentConfig[ext]
+			rule.Block = []BlockComment{{Start: cfgSplit[1], End: cfgSplit[2]}}
+		} else if len(cfgSplit) == 2 {
+			return nil, ErrInvalidCommentStringMissingBlock
+		}
 
-			if len(cfgSplit) >= 1 && cfgSplit[0] != "" {
-				cfg.LineComment = append(commentConfig[ext].LineComment, cfgSplit[0])
-			}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// commentConfigFromRules creates the CommentConfig for each extension.
+// If an extension is in more than one rule, all are added to that extension.
+func commentConfigFromRules(rules []CommentRule) (map[string]finder.CommentConfig, error) {
+	// Replace the builtin rules with the default comments.
+	expandedRules := make([]CommentRule, 0, len(rules))
+	for _, rule := range rules {
+		if !rule.Builtin {
+			expandedRules = append(expandedRules, rule)
+			continue
+		}
+
+		builtinRules, err := parseCommentRules(defaultComments)
+		if err != nil {
+			return nil, err
+		}
+		expandedRules = append(expandedRules, builtinRules...)
+	}
+
+	commentConfig := make(map[string]finder.CommentConfig)
 
-			if len(cfgSplit) >= 3 {
-				if cfgSplit[1] == "" || cfgSplit[2] == "" {
-					return nil, ErrInvalidCommentStringMissingBlock
+	for _, rule := range expandedRules {
+		extensions := rule.Ext
+		if len(extensions) == 0 {
+			// The catch-all rule.
+			extensions = []string{""}
+		}
+
+		for _, ext := range extensions {
+			ext = "." + strings.TrimPrefix(ext, ".")
+
+			// Note that the extension is also added if the rule is empty.
+			// That way the catch-all can be disabled for specific extensions.
+			cfg := commentConfig[ext]
+			cfg.LineComment = append(cfg.LineComment, rule.Line...)
+
+			for _, block := range rule.Block {
+				if block.Start == "" || block.End == "" {
+					return nil, ErrInvalidCommentRule
 				}
 
-				cfg.BlockStart = append(commentConfig[ext].BlockStart, cfgSplit[1])
-				cfg.BlockEnd = append(commentConfig[ext].BlockEnd, cfgSplit[2])
-			} else if len(cfgSplit) == 2 {
-				return nil, ErrInvalidCommentStringMissingBlock
+				cfg.BlockStart = append(cfg.BlockStart, block.Start)
+				cfg.BlockEnd = append(cfg.BlockEnd, block.End)
 			}
 
-			// If no config was found, just don't add the cfg.
-			if len(cfg.LineComment) > 0 || len(cfg.BlockStart) > 0 {
-				// Replace back the commaPlaceholder
-				cfg.LineComment = replaceInSlice(cfg.LineComment, commaPlaceholder, ",")
-				cfg.BlockStart = replaceInSlice(cfg.BlockStart, commaPlaceholder, ",")
-				cfg.BlockEnd = replaceInSlice(cfg.BlockEnd, commaPlaceholder, ",")
-				commentConfig[ext] = cfg
-			}
+			commentConfig[ext] = cfg
 		}
 	}
 
@@ -4,7 +4,7 @@ import (
 	"path/filepath"
 
 	"github.com/Tiffinger-Thiel-GmbH/atwhy/core"
-	"github.com/Tiffinger-Thiel-GmbH/atwhy/generator"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/loader"
 	"github.com/spf13/afero"
 
 	"github.com/spf13/cobra"
@@ -29,62 +29,49 @@ It allows you to include documentation from anywhere in the project
 and therefore provides a way to use "single source of truth" also for documentation.
 
 Templates define how to combine the documentation annotations from all over the project.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		templateFolder, projectPath, extensions, commentConfig, err := LoadCommonArgs(cmd)
+	// The errors are printed by Execute.
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := LoadCommonArgs(cmd)
 		if err != nil {
-			cmd.PrintErrln(err)
-			return
+			return err
 		}
 
-		var gen core.Generator
-		generatorType, err := cmd.Flags().GetString("generator")
+		gen, err := NewGenerator(config.Generator)
 		if err != nil {
-			cmd.PrintErrln(err)
-			return
+			return err
 		}
 
-		switch generatorType {
-		case "md":
-			gen = generator.Markdown{}
-		case "html":
-			gen = &generator.HTML{
-				Markdown: generator.Markdown{},
-			}
-		}
-
-		atwhy, err := core.New(gen, projectPath, "/", templateFolder, extensions, commentConfig)
+		atwhy, err := core.New(gen, config.ProjectPath, "/", config.TemplatesFolder, config.Extensions, config.CommentConfig, config.CoreOptions())
 		if err != nil {
-			cmd.PrintErr(err)
-			return
+			return err
 		}
 
-		templates, err := atwhy.Load()
+		templates, err := load(cmd, &atwhy, config.Strict)
 		if err != nil {
-			cmd.PrintErr(err)
-			return
+			return err
 		}
 
+		outputFS := afero.NewBasePathFs(afero.NewOsFs(), filepath.Join(config.ProjectPath, config.Output))
 		for _, t := range templates {
-			projectFS := afero.NewBasePathFs(afero.NewOsFs(), projectPath)
-
-			err := projectFS.MkdirAll(t.Path, 0775)
+			err := outputFS.MkdirAll(t.Path, 0775)
 			if err != nil {
-				cmd.PrintErr(err)
-				return
+				return err
 			}
-			filename := filepath.Join(t.Path, t.Name+atwhy.Generator.Ext())
-			file, err := projectFS.Create(filename)
+			file, err := outputFS.Create(atwhy.OutputFile(t))
 			if err != nil {
-				cmd.PrintErr(err)
-				return
+				return err
 			}
 
 			err = atwhy.Generate(t, file)
+			file.Close()
 			if err != nil {
-				cmd.PrintErr(err)
-				return
+				return err
 			}
 		}
+
+		return nil
 	},
 }
 
@@ -96,10 +83,23 @@ func Execute() {
 
 // init is run by Go on startup. https://tutorialedge.net/golang/the-go-init-function/
 func init() {
-	// Global flags.
-	rootCmd.PersistentFlags().StringP("templates-folder", "t", "templates", "path to a folder which contains the templates relative to the project directory")
-	rootCmd.PersistentFlags().StringSliceP("ext", "e", nil, "comma separated list of allowed extensions\nallow all if not provided\nexample: .go,.js,.ts")
-	rootCmd.PersistentFlags().StringP("project", "p", "", "the project folder")
+	addCommonFlags(rootCmd)
+
+	// Flags only for the root cmd.
+	addGenerateFlags(rootCmd)
+}
+
+// addCommonFlags adds the flags used by LoadCommonArgs as persistent flags.
+func addCommonFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringP("templates-folder", "t", "templates", "path to a folder which contains the templates relative to the project directory")
+	cmd.PersistentFlags().StringSliceP("ext", "e", nil, "comma separated list of allowed extensions\nallow all if not provided\nexample: .go,.js,.ts")
+	cmd.PersistentFlags().StringP("project", "p", "", "the project folder")
+	cmd.PersistentFlags().Bool("strict", false, "treat warnings as errors")
+	cmd.PersistentFlags().String("ref", "", "a git commit, tag or branch to read the project files and templates from instead of the working tree\nthe config file is still read from the working tree")
+	cmd.PersistentFlags().Bool("cache", false, "keep the found tags in the folder "+loader.CacheFolder+" of the project to only scan changed files in the next runs")
+	cmd.PersistentFlags().String("duplicates", "warning", "the severity used for placeholders which are used by more than one tag\npossible values are: 'warning', 'error'")
+	cmd.PersistentFlags().String("missing", "error", "the severity used for tags which are referenced by a template but do not exist\npossible values are: 'warning', 'error'")
+	cmd.PersistentFlags().String("config", "", "path to a config file\ndefault is the "+configFile+" in the project folder if it exists")
 
 	// @WHY readme_comments
 	// Each `--comment` is a string with the following format:
@@ -124,7 +124,7 @@ func init() {
 	// If `--comment` is passed at least one time, all built-in rules are disabled.
 	// Use `--comment=DEFAULT` if you still want to use the built-in rules.
 
-	rootCmd.PersistentFlags().StringArray("comment", nil, `Set the comments for a specific file ending.
+	cmd.PersistentFlags().StringArray("comment", nil, `Set the comments for a specific file ending.
 Syntax: 
 {extList}:{lineComment},{blockStart},{blockEnd}
 
@@ -147,6 +147,4 @@ Examples:
 If "--comment" is passed at least one time, all built-in rules are disabled.
 Use "--comment=DEFAULT" if you still want to use the built-in rules.
 `)
-	// Flags only for the root cmd.
-	rootCmd.Flags().StringP("generator", "g", "md", "the generator to use\npossible values are: 'md', 'html'")
 }
@@ -1,7 +1,11 @@
 package cmd
 
 import (
+	"context"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/Tiffinger-Thiel-GmbH/atwhy/core"
 	"github.com/Tiffinger-Thiel-GmbH/atwhy/generator"
@@ -15,6 +19,8 @@ import (
 // ```bash
 // atwhy serve
 // ```
+// The project and the templates are watched for changes and
+// open pages reload automatically.
 // For more information run `atwhy serve --help`
 
 // serveCmd allows to serve the documentation using a html webserver.
@@ -24,29 +30,59 @@ var serveCmd = &cobra.Command{
 	Long: `Serves the documentation using a webserver.
 It serves it on the given host. 
 (e.g. ":4444" to listen on all addresses, "localhost:4444" to listen only on localhost)
-Default is: "localhost:4444"`,
-	Run: func(cmd *cobra.Command, args []string) {
-		host := cmd.Flags().Arg(0)
-		if host == "" {
-			host = "localhost:4444"
-		}
+Default is: "localhost:4444" or the serve.host of the config file.
 
-		templateFolder, projectPath, extensions, commentConfig, err := LoadCommonArgs(cmd)
+Use --base-path if the server is behind a reverse proxy which forwards
+a sub path (e.g. "/docs/") to it.
+
+The project and the templates are watched for changes.
+Open pages reload automatically if something has changed.
+
+Project files are only served if they are not ignored by the .atwhyignore files
+and do not start with a dot (see --allow-dotfiles and --only-referenced).`,
+	Run: func(cmd *cobra.Command, args []string) {
+		config, err := LoadCommonArgs(cmd)
 		if err != nil {
 			cmd.PrintErrln(err)
 			return
 		}
 
+		host := cmd.Flags().Arg(0)
+		if host == "" {
+			host = config.Serve.Host
+		}
+		if host == "" {
+			host = "localhost:4444"
+		}
+
+		// The css for the highlighting is part of the page layout.
 		var gen core.Generator = &generator.HTML{
 			Markdown: generator.Markdown{},
+			Classes:  true,
 		}
-		atwhy, err := core.New(gen, projectPath, "/project/", templateFolder, extensions, commentConfig)
+		// The pages link their own copies of the project files.
+		options := config.CoreOptions()
+		options.SourceLink = ""
+
+		atwhy, err := core.New(gen, config.ProjectPath, "/project/", config.TemplatesFolder, config.Extensions, config.CommentConfig, options)
 		if err != nil {
 			cmd.PrintErr(err)
 			return
 		}
 
-		if err := atwhy.ListenAndServe(host); err != nil {
+		// Shut down gracefully on Ctrl+C.
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		err = atwhy.ListenAndServe(ctx, host, core.ServerOptions{
+			HandlerOptions: core.HandlerOptions{
+				BasePath:       config.Serve.BasePath,
+				LiveReload:     true,
+				AllowDotfiles:  config.Serve.AllowDotfiles,
+				OnlyReferenced: config.Serve.OnlyReferenced,
+			},
+		})
+		if err != nil {
 			log.Fatal(err)
 		}
 	},
@@ -57,4 +93,8 @@ Default is: "localhost:4444"`,
 // init is run by Go on startup. https://tutorialedge.net/golang/the-go-init-function/
 func init() {
 	rootCmd.AddCommand(serveCmd)
+	addHTMLFlags(serveCmd)
+	serveCmd.Flags().String("base-path", "", "the url path at which the documentation is served\ndefault is '/' or the serve.base-path of the config file")
+	serveCmd.Flags().Bool("allow-dotfiles", false, "also serve project files and folders starting with a dot (e.g. .env)\nfiles ignored by the .atwhyignore files are never served")
+	serveCmd.Flags().Bool("only-referenced", false, "only serve the project files which are linked by the pages")
 }
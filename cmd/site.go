@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"path/filepath"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/core"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/generator"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+// @WHY readme_usage4_site
+//
+// __Static site__
+// To publish the documentation on any static web host, run:
+// ```bash
+// atwhy site [OUTPUT (default: site)]
+// ```
+// It writes the same pages as `atwhy serve` into the output folder.
+// All links are relative, so the site can be hosted in any sub folder.
+// Files linked with `.Project` are copied into the `project` folder of the site
+// and pages with `server.index: true` in their header are additionally written as `index.html`.
+// All styles and scripts are included, so the pages (like the served ones) also work offline.
+// If the output folder has no `.atwhyignore` yet, one ignoring everything is added,
+// so that the copied files are not scanned for tags again.
+
+// siteCmd generates a static html site.
+var siteCmd = &cobra.Command{
+	Use:   "site [OUTPUT (e.g. site)]",
+	Short: "Generates the documentation as static html site.",
+	Long: `Generates the documentation as static html site.
+It uses the same layout as "atwhy serve" and writes it into the given folder
+relative to the project directory.
+Default is: "site" or the site.output of the config file.
+
+Project files linked by the templates are copied into the "project" folder of the site.`,
+	// The errors are printed by Execute.
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := LoadCommonArgs(cmd)
+		if err != nil {
+			return err
+		}
+
+		output := cmd.Flags().Arg(0)
+		if output == "" {
+			output = config.Site.Output
+		}
+		if output == "" {
+			output = "site"
+		}
+
+		// The css for the highlighting is part of the page layout.
+		var gen core.Generator = &generator.HTML{
+			Markdown: generator.Markdown{},
+			Classes:  true,
+		}
+		// The pages link their own copies of the project files.
+		options := config.CoreOptions()
+		options.SourceLink = ""
+
+		atwhy, err := core.New(gen, config.ProjectPath, "/", config.TemplatesFolder, config.Extensions, config.CommentConfig, options)
+		if err != nil {
+			return err
+		}
+
+		templates, err := load(cmd, &atwhy, config.Strict)
+		if err != nil {
+			return err
+		}
+
+		// Only print the new diagnostics of the build.
+		atwhy.Diagnostics.Reset()
+
+		outputFS := afero.NewBasePathFs(afero.NewOsFs(), filepath.Join(config.ProjectPath, output))
+		err = atwhy.BuildSite(outputFS, templates)
+		return printDiagnostics(cmd, &atwhy, config.Strict, err)
+	},
+
+	Args: cobra.MaximumNArgs(1),
+}
+
+// init is run by Go on startup. https://tutorialedge.net/golang/the-go-init-function/
+func init() {
+	rootCmd.AddCommand(siteCmd)
+	addHTMLFlags(siteCmd)
+}
@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/core"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/diagnostic"
+	mdTemplate "github.com/Tiffinger-Thiel-GmbH/atwhy/template"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+var ErrUnknownFormat = errors.New("unknown format, possible values are: 'table', 'json', 'yaml'")
+
+// maxTableValue is the maximum number of characters of the values shown in the table.
+const maxTableValue = 60
+
+// @WHY readme_usage7_tags
+//
+// __Tags__
+// To list all tags found in the project, run:
+// ```bash
+// atwhy tags
+// ```
+// It prints the placeholder, type, location and rendered value of each tag.
+// Problems like duplicate placeholders are printed as well (use `--strict` to fail in that case).
+// Broken templates are reported, but do not stop the listing.
+// Pass `--format json` or `--format yaml` to get all details (e.g. the templates using a tag) for scripts.
+// The tags can be filtered with `--prefix readme_`, `--type LINK` and `--path core/`.
+
+// tagsCmd lists all tags of the project.
+var tagsCmd = &cobra.Command{
+	Use:   "tags",
+	Short: "Lists all tags of the project.",
+	Long: `Lists all tags of the project.
+It prints the placeholder, type, location and rendered value of each tag as a table, json or yaml.
+Problems found while loading the tags and templates are printed to stderr.
+Json and yaml also contain the exact location and the templates which use each tag.
+
+The tags can be filtered by the prefix of the placeholder, the type and a file or folder.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := LoadCommonArgs(cmd)
+		if err != nil {
+			return err
+		}
+
+		format, err := cmd.Flags().GetString("format")
+		if err != nil {
+			return err
+		}
+
+		var filter tagFilter
+		filter.prefix, err = cmd.Flags().GetString("prefix")
+		if err != nil {
+			return err
+		}
+		filter.tagType, err = cmd.Flags().GetString("type")
+		if err != nil {
+			return err
+		}
+		filter.path, err = cmd.Flags().GetString("path")
+		if err != nil {
+			return err
+		}
+
+		atwhy, err := newSnapshotAtWhy(config, config.Ref)
+		if err != nil {
+			return err
+		}
+
+		// The problems are printed to explain e.g. why a template shows the wrong tag.
+		tags, err := atwhy.LoadTags()
+		if err != nil {
+			return printDiagnostics(cmd, &atwhy, config.Strict, err)
+		}
+
+		// The templates are only needed to list which of them use each tag,
+		// so broken templates are reported as warnings and the tags are still listed.
+		snapshot := core.Snapshot{Tags: tags}
+		snapshot.Templates, err = atwhy.TemplateLoader.Load(tags)
+		var d diagnostic.Diagnostic
+		if errors.As(err, &d) {
+			d.Severity = diagnostic.SeverityWarning
+			atwhy.Diagnostics.Report(d)
+		} else if err != nil {
+			return printDiagnostics(cmd, &atwhy, config.Strict, err)
+		}
+
+		infos, err := renderValues(filter.apply(core.TagInfos(snapshot)))
+		if err != nil {
+			return err
+		}
+
+		err = printTags(cmd.OutOrStdout(), infos, format)
+		if err != nil {
+			return err
+		}
+
+		return printDiagnostics(cmd, &atwhy, config.Strict, nil)
+	},
+}
+
+// renderValues replaces the values with the text shown in the templates,
+// e.g. the links of LINK tags relative to the project.
+func renderValues(infos []core.TagInfo) ([]core.TagInfo, error) {
+	for i, info := range infos {
+		value, err := mdTemplate.RenderValue(info.Value, "")
+		if err != nil {
+			return nil, fmt.Errorf("the value of %v is invalid: %w", info.Placeholder, err)
+		}
+		infos[i].Value = value
+	}
+	return infos, nil
+}
+
+// tagFilter selects the tags printed by the tags command.
+// Empty fields match all tags.
+type tagFilter struct {
+	prefix string
+	// tagType is compared case-insensitive.
+	tagType string
+	// path is a file or folder relative to the project.
+	path string
+}
+
+// apply returns only the tags matching the filter.
+func (f tagFilter) apply(infos []core.TagInfo) []core.TagInfo {
+	folder := path.Clean(filepath.ToSlash(f.path))
+
+	result := []core.TagInfo{}
+	for _, info := range infos {
+		if !strings.HasPrefix(info.Placeholder, f.prefix) {
+			continue
+		}
+		if f.tagType != "" && !strings.EqualFold(string(info.Type), f.tagType) {
+			continue
+		}
+		if f.path != "" && folder != "." && info.File != folder && !strings.HasPrefix(info.File, folder+"/") {
+			continue
+		}
+
+		result = append(result, info)
+	}
+	return result
+}
+
+// printTags writes the tags in the given format.
+func printTags(writer io.Writer, infos []core.TagInfo, format string) error {
+	switch format {
+	case "table":
+		return printTagTable(writer, infos)
+	case "json":
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(infos)
+	case "yaml":
+		encoder := yaml.NewEncoder(writer)
+		err := encoder.Encode(infos)
+		if err != nil {
+			return err
+		}
+		return encoder.Close()
+	}
+
+	return ErrUnknownFormat
+}
+
+// printTagTable writes one line per tag with the first line of its value.
+func printTagTable(writer io.Writer, infos []core.TagInfo) error {
+	table := tabwriter.NewWriter(writer, 0, 4, 2, ' ', 0)
+	_, err := fmt.Fprintln(table, "PLACEHOLDER\tTYPE\tLOCATION\tVALUE")
+	if err != nil {
+		return err
+	}
+
+	for _, info := range infos {
+		location := info.File + ":" + strconv.Itoa(info.Line)
+		_, err := fmt.Fprintf(table, "%v\t%v\t%v\t%v\n", info.Placeholder, info.Type, location, shortValue(info.Value))
+		if err != nil {
+			return err
+		}
+	}
+
+	return table.Flush()
+}
+
+// shortValue returns the first non-empty line of the value,
+// shortened to maxTableValue characters.
+func shortValue(value string) string {
+	for _, line := range strings.Split(value, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		runes := []rune(line)
+		if len(runes) > maxTableValue {
+			return string(runes[:maxTableValue-3]) + "..."
+		}
+		return line
+	}
+	return ""
+}
+
+// init is run by Go on startup. https://tutorialedge.net/golang/the-go-init-function/
+func init() {
+	tagsCmd.Flags().StringP("format", "f", "table", "the output format\npossible values are: 'table', 'json', 'yaml'")
+	tagsCmd.Flags().String("prefix", "", "only list tags whose placeholder starts with the prefix")
+	tagsCmd.Flags().String("type", "", "only list tags of the type\npossible values are: 'DOC', 'LINK', 'CODE'")
+	tagsCmd.Flags().String("path", "", "only list tags in the file or folder relative to the project directory")
+	rootCmd.AddCommand(tagsCmd)
+}
@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/core"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/finder"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_tagFilter_apply(t *testing.T) {
+	infos := []core.TagInfo{
+		{Placeholder: "readme_intro", Type: "DOC", File: "main.go"},
+		{Placeholder: "readme_link", Type: "LINK", File: "core/atwhy.go"},
+		{Placeholder: "other", Type: "DOC", File: "core2/other.go"},
+	}
+
+	tests := []struct {
+		name   string
+		filter tagFilter
+		want   []string
+	}{
+		{
+			name: "no filter",
+			want: []string{"readme_intro", "readme_link", "other"},
+		},
+		{
+			name:   "prefix",
+			filter: tagFilter{prefix: "readme_"},
+			want:   []string{"readme_intro", "readme_link"},
+		},
+		{
+			name:   "type is case-insensitive",
+			filter: tagFilter{tagType: "link"},
+			want:   []string{"readme_link"},
+		},
+		{
+			name:   "folder",
+			filter: tagFilter{path: "core/"},
+			want:   []string{"readme_link"},
+		},
+		{
+			name:   "file",
+			filter: tagFilter{path: "./main.go"},
+			want:   []string{"readme_intro"},
+		},
+		{
+			name:   "combined",
+			filter: tagFilter{prefix: "readme_", tagType: "DOC", path: "core"},
+			want:   []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, info := range tt.filter.apply(infos) {
+				got = append(got, info.Placeholder)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_printTags(t *testing.T) {
+	projectPath := t.TempDir()
+	files := map[string]string{
+		"main.go":                 "// @WHY hello\n// Hello\n// World\npackage main\n",
+		"templates/README.tpl.md": "# Readme\n{{ .Tag.hello }}\n",
+	}
+	for name, content := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Join(projectPath, filepath.Dir(name)), 0775))
+		assert.NoError(t, os.WriteFile(filepath.Join(projectPath, name), []byte(content), 0664))
+	}
+
+	snapshot, err := loadSnapshot(Config{
+		TemplatesFolder: "templates",
+		ProjectPath:     projectPath,
+		CommentConfig: map[string]finder.CommentConfig{
+			".go": {LineComment: []string{"//"}},
+		},
+	}, "")
+	assert.NoError(t, err)
+	infos := core.TagInfos(snapshot)
+
+	tests := []struct {
+		name    string
+		format  string
+		want    string
+		wantErr error
+	}{
+		{
+			name:   "table",
+			format: "table",
+			want: `PLACEHOLDER  TYPE  LOCATION   VALUE
+hello        DOC   main.go:1  Hello
+`,
+		},
+		{
+			name:   "json",
+			format: "json",
+			want: `[
+  {
+    "placeholder": "hello",
+    "type": "DOC",
+    "file": "main.go",
+    "line": 1,
+    "column": 4,
+    "endLine": 3,
+    "endColumn": 8,
+    "value": "Hello  \nWorld",
+    "templates": [
+      "templates/README.tpl.md"
+    ],
+    "unused": false
+  }
+]
+`,
+		},
+		{
+			name:   "yaml",
+			format: "yaml",
+			want: `- placeholder: hello
+  type: DOC
+  file: main.go
+  line: 1
+  column: 4
+  endLine: 3
+  endColumn: 8
+  value: "Hello  \nWorld"
+  templates:
+  - templates/README.tpl.md
+  unused: false
+`,
+		},
+		{
+			name:    "unknown format",
+			format:  "xml",
+			wantErr: ErrUnknownFormat,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := &bytes.Buffer{}
+			err := printTags(writer, infos, tt.format)
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr == nil {
+				assert.Equal(t, tt.want, writer.String())
+			}
+		})
+	}
+}
+
+func Test_shortValue(t *testing.T) {
+	assert.Equal(t, "first", shortValue("\n  first  \nsecond"))
+	assert.Equal(t, "", shortValue(""))
+	long := "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	assert.Equal(t, long[:maxTableValue-3]+"...", shortValue(long))
+}
+
+func Test_renderValues(t *testing.T) {
+	infos := []core.TagInfo{
+		{Placeholder: "link", Value: `[cmd/a.go:10]({{ .Project "cmd/a.go" 10 10 }})`},
+		{Placeholder: "escaped", Value: `{{ .Escape "{{" }}`},
+	}
+
+	got, err := renderValues(infos)
+	assert.NoError(t, err)
+	assert.Equal(t, "[cmd/a.go:10](cmd/a.go)", got[0].Value)
+	assert.Equal(t, "{{", got[1].Value)
+
+	_, err = renderValues([]core.TagInfo{{Placeholder: "invalid", Value: "{{ .Foo "}})
+	assert.Error(t, err)
+}
+
+func Test_tagsCmd_diagnostics(t *testing.T) {
+	projectPath := t.TempDir()
+	files := map[string]string{
+		"main.go":                 "// @WHY hello\n// Hello\n\n// @WHY hello\n// Duplicate\npackage main\n",
+		"templates/README.tpl.md": "# Readme\n{{ .Tag.hello }}\n",
+	}
+	for name, content := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Join(projectPath, filepath.Dir(name)), 0775))
+		assert.NoError(t, os.WriteFile(filepath.Join(projectPath, name), []byte(content), 0664))
+	}
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	rootCmd.SetOut(stdout)
+	rootCmd.SetErr(stderr)
+	rootCmd.SetArgs([]string{"tags", "-p", projectPath, "--ext", ".go", "--strict"})
+	t.Cleanup(func() {
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+		rootCmd.SetArgs(nil)
+		assert.NoError(t, rootCmd.PersistentFlags().Set("strict", "false"))
+	})
+
+	err := rootCmd.Execute()
+	assert.ErrorIs(t, err, ErrStrict)
+	assert.Contains(t, stdout.String(), "main.go:1")
+	assert.Contains(t, stderr.String(), "main.go:4: warning: the placeholder hello is already used at main.go:1")
+}
+
+func Test_tagsCmd_brokenTemplate(t *testing.T) {
+	projectPath := t.TempDir()
+	files := map[string]string{
+		"main.go":                 "// @WHY hello\n// Hello\npackage main\n",
+		"templates/README.tpl.md": "# Readme\n{{ .Tag.hello }\n",
+	}
+	for name, content := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Join(projectPath, filepath.Dir(name)), 0775))
+		assert.NoError(t, os.WriteFile(filepath.Join(projectPath, name), []byte(content), 0664))
+	}
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	rootCmd.SetOut(stdout)
+	rootCmd.SetErr(stderr)
+	rootCmd.SetArgs([]string{"tags", "-p", projectPath, "--ext", ".go"})
+	t.Cleanup(func() {
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+		rootCmd.SetArgs(nil)
+	})
+
+	err := rootCmd.Execute()
+	assert.NoError(t, err)
+	assert.Contains(t, stdout.String(), "main.go:1  Hello")
+	assert.Contains(t, stderr.String(), "templates/README.tpl.md:2: warning:")
+}
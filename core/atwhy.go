@@ -1,11 +1,18 @@
 package core
 
 import (
-	"html/template"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"path/filepath"
 
 	"github.com/Tiffinger-Thiel-GmbH/atwhy/core/tag"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/diagnostic"
 	"github.com/Tiffinger-Thiel-GmbH/atwhy/finder"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/gitfs"
 	"github.com/Tiffinger-Thiel-GmbH/atwhy/loader"
 	mdTemplate "github.com/Tiffinger-Thiel-GmbH/atwhy/template"
 	"github.com/spf13/afero"
@@ -51,24 +58,107 @@ type AtWhy struct {
 	Generator      Generator
 	TemplateLoader TemplateLoader
 
-	projectPath       string
+	// Diagnostics collects all warnings of the last Load.
+	Diagnostics *diagnostic.Collector
+
 	projectPathPrefix string
-	pageTemplate      *template.Template
+
+	// ref is empty if the working tree is used.
+	ref string
+
+	projectFS  afero.Fs
+	templateFS afero.Fs
+
+	// themeFS is nil if no theme is used.
+	themeFS afero.Fs
+
+	// pages caches the loaded templates in serve mode.
+	pages *pageCache
 }
 
 // @WHY CODE_END
 
-func New(gen Generator, projectPath string, projectPathPrefix string, templateFolder string, extensions []string, commentConfig map[string]finder.CommentConfig) (AtWhy, error) {
-	filesystem := afero.NewBasePathFs(afero.NewOsFs(), projectPath)
+// Options contains the settings for New which are not needed in most cases.
+// The zero value uses the defaults.
+type Options struct {
+	// DuplicateSeverity is used to report placeholders which are used by more than one tag.
+	// Default is diagnostic.SeverityWarning.
+	DuplicateSeverity diagnostic.Severity
+
+	// MissingSeverity is used to report templates which reference tags that do not exist.
+	// Default is diagnostic.SeverityError.
+	MissingSeverity diagnostic.Severity
+
+	// ThemeFolder is the path of a theme for the html pages relative to the project.
+	// Default is the built-in theme.
+	ThemeFolder string
+
+	// Cache keeps the tags of all files in the loader.CacheFolder of the project,
+	// so that only changed files are scanned again.
+	// It is not used together with a Ref.
+	Cache bool
+
+	// Ref is a git revision (e.g. a commit, tag or branch).
+	// If it is set, the project files and templates are read from it instead of the working tree.
+	Ref string
+
+	// SourceLink is a url pattern used for the links to project files in the generated files,
+	// e.g. "https://github.com/{repo}/blob/{commit}/{path}#L{line}".
+	// The commit is the one of the Ref or of the git HEAD.
+	// Default is a link relative to the generated files.
+	SourceLink string
+
+	// Overlay contains files which replace the files of the project with the same path
+	// (relative to the project), e.g. the unsaved files of an editor.
+	// It is not used together with a Ref or the Cache.
+	Overlay afero.Fs
+}
+
+func New(gen Generator, projectPath string, projectPathPrefix string, templateFolder string, extensions []string, commentConfig map[string]finder.CommentConfig, options Options) (AtWhy, error) {
+	var filesystem afero.Fs = afero.NewBasePathFs(afero.NewOsFs(), projectPath)
+	if options.Ref != "" {
+		var err error
+		filesystem, err = gitfs.New(projectPath, options.Ref)
+		if err != nil {
+			return AtWhy{}, err
+		}
+	} else if options.Overlay != nil {
+		// The base path makes the relative names of the loader absolute, as they are used by e.g. afero.MemMapFs.
+		filesystem = afero.NewCopyOnWriteFs(filesystem, afero.NewBasePathFs(options.Overlay, "/"))
+	}
 	templateFS := afero.NewBasePathFs(filesystem, templateFolder)
+	diagnostics := &diagnostic.Collector{}
+
+	var cache *loader.Cache
+	// The files of a ref never change and the cache could not be written into it.
+	// The files of the overlay change too often to be cached.
+	if options.Cache && options.Ref == "" && options.Overlay == nil {
+		key, err := cacheKey(commentConfig)
+		if err != nil {
+			return AtWhy{}, err
+		}
+		cache = &loader.Cache{Key: key}
+	}
+
+	var sourceLinkFunc mdTemplate.SourceLinkFunc
+	if options.SourceLink != "" {
+		link, err := newSourceLink(options.SourceLink, projectPath, options.Ref)
+		if err != nil {
+			return AtWhy{}, err
+		}
+		sourceLinkFunc = link.URL
+	}
 
 	atwhy := AtWhy{
 		Finder: &finder.Finder{
 			CommentConfig: commentConfig,
+			Reporter:      diagnostics,
 		},
 		Loader: loader.File{
 			FS:             filesystem,
 			FileExtensions: extensions,
+			Cache:          cache,
+			Reporter:       diagnostics,
 		},
 		TagFactories: []tag.Factory{
 			tag.Doc,
@@ -79,13 +169,36 @@ func New(gen Generator, projectPath string, projectPathPrefix string, templateFo
 		TemplateLoader: mdTemplate.Loader{
 			FS:                templateFS,
 			ProjectPathPrefix: projectPathPrefix,
+			SourceLink:        sourceLinkFunc,
+			Folder:            templateFolder,
+			Reporter:          diagnostics,
+			DuplicateSeverity: options.DuplicateSeverity,
+			MissingSeverity:   options.MissingSeverity,
 		},
+		Diagnostics: diagnostics,
 
-		projectPath:       projectPath,
 		projectPathPrefix: projectPathPrefix,
+		ref:               options.Ref,
+
+		projectFS:  filesystem,
+		templateFS: templateFS,
+
+		pages: &pageCache{},
+	}
+
+	if options.ThemeFolder != "" {
+		exists, err := afero.DirExists(filesystem, options.ThemeFolder)
+		if err != nil {
+			return AtWhy{}, err
+		}
+		if !exists {
+			return AtWhy{}, fmt.Errorf("%w: %v", ErrThemeNotFound, options.ThemeFolder)
+		}
+		atwhy.themeFS = afero.NewBasePathFs(filesystem, options.ThemeFolder)
 	}
 
-	err := atwhy.initPageTemplate()
+	// Check the layouts early, even if they are parsed again for each page.
+	_, err := atwhy.parseLayouts()
 	if err != nil {
 		return AtWhy{}, err
 	}
@@ -93,7 +206,34 @@ func New(gen Generator, projectPath string, projectPathPrefix string, templateFo
 	return atwhy, nil
 }
 
+// cacheKey identifies the comment configuration, so that the cache is dropped when it changes.
+func cacheKey(commentConfig map[string]finder.CommentConfig) (string, error) {
+	// The keys of maps are sorted by json, so the result is always the same.
+	config, err := json.Marshal(commentConfig)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(config)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// Load all tags and templates.
+// Warnings are collected in the Diagnostics.
 func (a *AtWhy) Load() ([]mdTemplate.Markdown, error) {
+	tags, err := a.LoadTags()
+	if err != nil {
+		return nil, err
+	}
+
+	return a.TemplateLoader.Load(tags)
+}
+
+// LoadTags loads only the tags of the project.
+// Warnings are collected in the Diagnostics.
+func (a *AtWhy) LoadTags() ([]tag.Tag, error) {
+	a.Diagnostics.Reset()
+
 	tags, err := a.Loader.Load(a.Finder)
 	if err != nil {
 		return nil, err
@@ -106,7 +246,10 @@ func (a *AtWhy) Load() ([]mdTemplate.Markdown, error) {
 
 		for _, factory := range a.TagFactories {
 			newTag, err := factory(t)
-			if err != nil {
+			var d diagnostic.Diagnostic
+			if errors.As(err, &d) && d.Severity == diagnostic.SeverityWarning {
+				a.Diagnostics.Report(d)
+			} else if err != nil {
 				return nil, err
 			}
 			if newTag == nil {
@@ -117,9 +260,15 @@ func (a *AtWhy) Load() ([]mdTemplate.Markdown, error) {
 		}
 	}
 
-	return a.TemplateLoader.Load(processed)
+	return processed, nil
 }
 
 func (a *AtWhy) Generate(template mdTemplate.Markdown, writer io.Writer) error {
 	return a.Generator.Generate(template, writer)
 }
+
+// OutputFile returns the path of the file generated from the given template,
+// relative to the project root.
+func (a *AtWhy) OutputFile(template mdTemplate.Markdown) string {
+	return filepath.Join(template.Path, template.Name+a.Generator.Ext())
+}
@@ -1,14 +1,20 @@
 package core_test
 
 import (
+	"bytes"
 	"io"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/Tiffinger-Thiel-GmbH/atwhy/core"
 	"github.com/Tiffinger-Thiel-GmbH/atwhy/finder"
 	"github.com/Tiffinger-Thiel-GmbH/atwhy/generator"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/gitfs"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/loader"
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 )
@@ -23,7 +29,7 @@ func Test_core(t *testing.T) {
 			BlockStart:  []string{"/*"},
 			BlockEnd:    []string{"*/"},
 		},
-	})
+	}, core.Options{})
 	assert.NoError(t, err)
 
 	templates, err := atwhy.Load()
@@ -48,3 +54,96 @@ func Test_core(t *testing.T) {
 		assert.Greater(t, len(content), 10)
 	}
 }
+
+func TestNew_ref(t *testing.T) {
+	projectPath := t.TempDir()
+	repo, err := git.PlainInit(projectPath, false)
+	assert.NoError(t, err)
+	worktree, err := repo.Worktree()
+	assert.NoError(t, err)
+
+	writeFiles := func(files map[string]string) {
+		for name, content := range files {
+			assert.NoError(t, os.MkdirAll(filepath.Join(projectPath, filepath.Dir(name)), 0775))
+			assert.NoError(t, os.WriteFile(filepath.Join(projectPath, name), []byte(content), 0664))
+		}
+	}
+
+	writeFiles(map[string]string{
+		"main.go":                 "// @WHY version\n// 1.0.0\npackage main\n",
+		"templates/README.tpl.md": "# Version {{ .Tag.version }}",
+	})
+	assert.NoError(t, worktree.AddGlob("."))
+	_, err = worktree.Commit("release", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	assert.NoError(t, err)
+
+	// Changes of the working tree are not used.
+	writeFiles(map[string]string{
+		"main.go":                 "// @WHY version\n// 2.0.0\npackage main\n",
+		"templates/README.tpl.md": "# Unreleased {{ .Tag.version }}",
+	})
+
+	atwhy, err := core.New(generator.Markdown{}, projectPath, "/", "templates", nil, map[string]finder.CommentConfig{
+		".go": {LineComment: []string{"//"}},
+	}, core.Options{Ref: "HEAD", Cache: true})
+	assert.NoError(t, err)
+
+	templates, err := atwhy.Load()
+	assert.NoError(t, err)
+	assert.Len(t, templates, 1)
+
+	var buf bytes.Buffer
+	assert.NoError(t, atwhy.Generate(templates[0], &buf))
+	assert.Contains(t, buf.String(), "# Version 1.0.0")
+
+	_, err = core.New(generator.Markdown{}, projectPath, "/", "templates", nil, nil, core.Options{Ref: "unknown"})
+	assert.ErrorIs(t, err, gitfs.ErrRevisionNotFound)
+}
+
+func TestNew_overlay(t *testing.T) {
+	projectPath := t.TempDir()
+	files := map[string]string{
+		"main.go":                 "// @WHY version\n// 1.0.0\npackage main\n",
+		"other.go":                "// @WHY other\n// other\npackage main\n",
+		"templates/README.tpl.md": "# Version {{ .Tag.version }}",
+	}
+	for name, content := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Join(projectPath, filepath.Dir(name)), 0775))
+		assert.NoError(t, os.WriteFile(filepath.Join(projectPath, name), []byte(content), 0664))
+	}
+
+	// The overlay replaces existing files and adds new ones.
+	overlay := afero.NewMemMapFs()
+	assert.NoError(t, afero.WriteFile(overlay, "/main.go", []byte("// @WHY version\n// 2.0.0\npackage main\n"), 0664))
+	assert.NoError(t, afero.WriteFile(overlay, "/templates/docs/Other.tpl.md", []byte("{{ .Tag.other }}"), 0664))
+
+	atwhy, err := core.New(generator.Markdown{}, projectPath, "/", "templates", nil, map[string]finder.CommentConfig{
+		".go": {LineComment: []string{"//"}},
+	}, core.Options{Overlay: overlay, Cache: true})
+	assert.NoError(t, err)
+
+	templates, err := atwhy.Load()
+	assert.NoError(t, err)
+	assert.Len(t, templates, 2)
+
+	for _, tpl := range templates {
+		var buf bytes.Buffer
+		assert.NoError(t, atwhy.Generate(tpl, &buf))
+		switch tpl.Name {
+		case "README":
+			assert.Equal(t, "# Version 2.0.0\n", buf.String())
+		case "Other":
+			assert.Equal(t, "other\n", buf.String())
+		default:
+			t.Errorf("unexpected template %v", tpl.Name)
+		}
+	}
+
+	// Nothing is written into the project.
+	content, err := os.ReadFile(filepath.Join(projectPath, "main.go"))
+	assert.NoError(t, err)
+	assert.Equal(t, files["main.go"], string(content))
+	assert.NoDirExists(t, filepath.Join(projectPath, loader.CacheFolder))
+}
@@ -0,0 +1,144 @@
+package core
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/core/tag"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/diagnostic"
+	mdTemplate "github.com/Tiffinger-Thiel-GmbH/atwhy/template"
+)
+
+// ChangeKind describes how a tag has changed between two Snapshots.
+type ChangeKind string
+
+const (
+	ChangeAdded   ChangeKind = "added"
+	ChangeRemoved ChangeKind = "removed"
+	ChangeChanged ChangeKind = "changed"
+)
+
+// Snapshot contains all tags and templates of the project at one point in time.
+type Snapshot struct {
+	Tags      []tag.Tag
+	Templates []mdTemplate.Markdown
+
+	// Diagnostics are the warnings found while loading.
+	Diagnostics []diagnostic.Diagnostic
+}
+
+// TagChange is a placeholder which differs between two Snapshots.
+type TagChange struct {
+	Placeholder string
+	Kind        ChangeKind
+
+	// Old and New are the values of the tag. Old is empty for added tags and New for removed ones.
+	// If several tags use the same placeholder, their values are combined in the order of their location.
+	Old string
+	New string
+
+	// Templates are the files of all templates which use the tag (also through their partials), relative to the project.
+	// They are taken from both Snapshots, so templates which stopped using the tag are included.
+	Templates []string
+}
+
+// Snapshot loads all tags and templates.
+// Warnings are collected in the Diagnostics.
+func (a *AtWhy) Snapshot() (Snapshot, error) {
+	tags, err := a.LoadTags()
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	templates, err := a.TemplateLoader.Load(tags)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	return Snapshot{
+		Tags:        tags,
+		Templates:   templates,
+		Diagnostics: a.Diagnostics.Diagnostics(),
+	}, nil
+}
+
+// DiffTags compares the tags of both Snapshots.
+// The changes are sorted by their placeholder.
+func DiffTags(from, to Snapshot) []TagChange {
+	oldValues := tagValues(from.Tags)
+	newValues := tagValues(to.Tags)
+
+	var placeholders []string
+	for placeholder := range oldValues {
+		placeholders = append(placeholders, placeholder)
+	}
+	for placeholder := range newValues {
+		if _, ok := oldValues[placeholder]; !ok {
+			placeholders = append(placeholders, placeholder)
+		}
+	}
+	sort.Strings(placeholders)
+
+	references := append(mdTemplate.UsedReferences(from.Templates), mdTemplate.UsedReferences(to.Templates)...)
+
+	var changes []TagChange
+	for _, placeholder := range placeholders {
+		oldValue, inOld := oldValues[placeholder]
+		newValue, inNew := newValues[placeholder]
+
+		change := TagChange{
+			Placeholder: placeholder,
+			Old:         oldValue,
+			New:         newValue,
+		}
+
+		switch {
+		case !inOld:
+			change.Kind = ChangeAdded
+		case !inNew:
+			change.Kind = ChangeRemoved
+		case oldValue != newValue:
+			change.Kind = ChangeChanged
+		default:
+			continue
+		}
+
+		templates := make(map[string]bool)
+		for _, r := range references {
+			if r.Matches(placeholder) {
+				templates[r.Template] = true
+			}
+		}
+		for file := range templates {
+			change.Templates = append(change.Templates, file)
+		}
+		sort.Strings(change.Templates)
+
+		changes = append(changes, change)
+	}
+
+	return changes
+}
+
+// tagValues maps the placeholders to the values of their tags.
+func tagValues(tags []tag.Tag) map[string]string {
+	sorted := make([]tag.Tag, len(tags))
+	copy(sorted, tags)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].File() != sorted[j].File() {
+			return sorted[i].File() < sorted[j].File()
+		}
+		return sorted[i].Line() < sorted[j].Line()
+	})
+
+	values := make(map[string][]string)
+	for _, t := range sorted {
+		values[t.Placeholder()] = append(values[t.Placeholder()], t.String())
+	}
+
+	result := make(map[string]string, len(values))
+	for placeholder, v := range values {
+		result[placeholder] = strings.Join(v, "\n")
+	}
+	return result
+}
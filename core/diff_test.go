@@ -0,0 +1,71 @@
+package core_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/core"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/finder"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/generator"
+	"github.com/stretchr/testify/assert"
+)
+
+func testSnapshot(t *testing.T, files map[string]string) core.Snapshot {
+	projectPath := t.TempDir()
+	for name, content := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Join(projectPath, filepath.Dir(name)), 0775))
+		assert.NoError(t, os.WriteFile(filepath.Join(projectPath, name), []byte(content), 0664))
+	}
+
+	atwhy, err := core.New(generator.Markdown{}, projectPath, "/", "templates", nil, map[string]finder.CommentConfig{
+		".go": {LineComment: []string{"//"}},
+	}, core.Options{})
+	assert.NoError(t, err)
+
+	snapshot, err := atwhy.Snapshot()
+	assert.NoError(t, err)
+	return snapshot
+}
+
+func TestDiffTags(t *testing.T) {
+	from := testSnapshot(t, map[string]string{
+		"main.go":                 "// @WHY same\n// same\n\n// @WHY changed\n// old\n\n// @WHY removed\n// removed\n\n// @WHY +list\n// one\npackage main\n",
+		"templates/README.tpl.md": "{{ .Tag.same }} {{ .Tag.changed }} {{ .Tag.removed }} {{ .Tag.list }}",
+		"templates/Other.tpl.md":  "{{ .Tag.changed }}",
+	})
+	to := testSnapshot(t, map[string]string{
+		"main.go":                 "// @WHY same\n// same\n\n// @WHY changed\n// new\n\n// @WHY added\n// added\n\n// @WHY +list\n// one\n\n// @WHY +list\n// two\npackage main\n",
+		"templates/README.tpl.md": "{{ .Tag.same }} {{ .Tag.changed }} {{ .Tag.added }} {{ .Tag.list }}",
+		"templates/All.tpl.md":    "{{ range .Tag }}{{ . }}{{ end }}",
+	})
+
+	assert.Equal(t, []core.TagChange{
+		{
+			Placeholder: "added",
+			Kind:        core.ChangeAdded,
+			New:         "added",
+			Templates:   []string{"templates/All.tpl.md", "templates/README.tpl.md"},
+		},
+		{
+			Placeholder: "changed",
+			Kind:        core.ChangeChanged,
+			Old:         "old",
+			New:         "new",
+			Templates:   []string{"templates/All.tpl.md", "templates/Other.tpl.md", "templates/README.tpl.md"},
+		},
+		{
+			Placeholder: "list",
+			Kind:        core.ChangeChanged,
+			Old:         "one",
+			New:         "one\ntwo",
+			Templates:   []string{"templates/All.tpl.md", "templates/README.tpl.md"},
+		},
+		{
+			Placeholder: "removed",
+			Kind:        core.ChangeRemoved,
+			Old:         "removed",
+			Templates:   []string{"templates/All.tpl.md", "templates/README.tpl.md"},
+		},
+	}, core.DiffTags(from, to))
+}
@@ -0,0 +1,99 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/diagnostic"
+)
+
+// tagsPath is the path of the tag explorer, relative to the root of the site.
+const tagsPath = "_atwhy/tags"
+
+// apiPath is the path of the json api, relative to the root of the site.
+const apiPath = "_atwhy/api/"
+
+// @WHY readme_usage1_serve_tags
+// The server also shows an overview of all tags at `/_atwhy/tags`.
+// It lists the placeholder, type and location of each tag and the templates using it.
+// The tags can be searched and filtered, e.g. to find unused ones.
+//
+// The same information is available as json for other tools (e.g. editor plugins):
+// * `/_atwhy/api/tags`: all tags with their location, value, the templates using them and if they are unused.
+// * `/_atwhy/api/pages`: all pages with their template, output file and link.
+// * `/_atwhy/api/diagnostics`: all warnings found while loading the tags and templates.
+
+// serveTags renders the tag explorer with the tags layout.
+func (a *AtWhy) serveTags(w http.ResponseWriter, r *http.Request, basePath string, liveReload bool) {
+	snapshot, err := a.pages.get(a.loadAndLog)
+	if err != nil {
+		// TODO use a logger
+		fmt.Println(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data := a.pageData(snapshot.Templates, basePath, liveReload)
+	data.Title = "Tags"
+	data.Tags = a.serverTagInfos(snapshot, basePath)
+	data.Nav = data.buildNav()
+
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	err = a.executeLayout(w, tagsFile, data)
+	if err != nil {
+		// TODO use a logger
+		fmt.Println(err)
+	}
+}
+
+// serveAPI serves the json api.
+// The path of the request has to be relative to the apiPath.
+func (a *AtWhy) serveAPI(w http.ResponseWriter, r *http.Request, basePath string) {
+	snapshot, err := a.pages.get(a.loadAndLog)
+	if err != nil {
+		// TODO use a logger
+		fmt.Println(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var result interface{}
+	switch r.URL.Path {
+	case "tags":
+		result = a.serverTagInfos(snapshot, basePath)
+	case "pages":
+		pages := a.PageInfos(snapshot)
+		for i := range pages {
+			pages[i].Link = PageData{BasePath: basePath}.Link(snapshot.Templates[i])
+		}
+		result = pages
+	case "diagnostics":
+		diagnostics := snapshot.Diagnostics
+		if diagnostics == nil {
+			diagnostics = []diagnostic.Diagnostic{}
+		}
+		result = diagnostics
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(result)
+	if err != nil {
+		// TODO use a logger
+		fmt.Println(err)
+	}
+}
+
+// serverTagInfos returns the TagInfos with links to the source viewer.
+func (a *AtWhy) serverTagInfos(snapshot Snapshot, basePath string) []TagInfo {
+	link := a.viewerLink(basePath)
+
+	infos := TagInfos(snapshot)
+	for i := range infos {
+		infos[i].Link = link(infos[i].File, infos[i].Line, infos[i].EndLine)
+	}
+	return infos
+}
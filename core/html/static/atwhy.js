@@ -0,0 +1,30 @@
+// Toggles the navigation on small screens.
+document.querySelectorAll("[data-bs-toggle=collapse]").forEach(function (toggler) {
+    toggler.addEventListener("click", function () {
+        var target = document.querySelector(toggler.getAttribute("data-bs-target"));
+        var expanded = target.classList.toggle("show");
+        toggler.setAttribute("aria-expanded", expanded);
+    });
+});
+
+// Filters the table of the tag explorer.
+var tagTable = document.getElementById("tags");
+if (tagTable) {
+    var tagSearch = document.getElementById("tag-search");
+    var tagType = document.getElementById("tag-type");
+    var tagUnused = document.getElementById("tag-unused");
+
+    var filterTags = function () {
+        var query = tagSearch.value.toLowerCase();
+        tagTable.querySelectorAll("tbody tr").forEach(function (row) {
+            row.hidden = row.textContent.toLowerCase().indexOf(query) < 0 ||
+                (tagType.value !== "" && row.getAttribute("data-type") !== tagType.value) ||
+                (tagUnused.checked && row.getAttribute("data-unused") !== "true");
+        });
+    };
+
+    [tagSearch, tagType, tagUnused].forEach(function (input) {
+        input.addEventListener("input", filterTags);
+        input.addEventListener("change", filterTags);
+    });
+}
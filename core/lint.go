@@ -0,0 +1,111 @@
+package core
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/diagnostic"
+	mdTemplate "github.com/Tiffinger-Thiel-GmbH/atwhy/template"
+)
+
+// LintRule describes a code of the diagnostics which can be configured for the linter.
+type LintRule struct {
+	Code        string
+	Description string
+}
+
+// LintRules are all codes reported by Lint.
+var LintRules = []LintRule{
+	{Code: "invalid-tag", Description: "a @WHY which doesn't match the required format, e.g. an invalid placeholder"},
+	{Code: "duplicate-placeholder", Description: "a placeholder which is used by more than one tag"},
+	{Code: "unused-tag", Description: "a tag which is not used by any template"},
+	{Code: "missing-tag", Description: "a template which references a tag that does not exist"},
+	{Code: "empty-tag", Description: "a tag without content"},
+	{Code: "unclosed-code", Description: "a CODE tag without CODE_END"},
+	{Code: "unexpected-code-end", Description: "a CODE_END without CODE tag"},
+	{Code: "unknown-header-key", Description: "an unknown key in the yaml header of a template"},
+	{Code: "invalid-header", Description: "an invalid yaml header of a template"},
+	{Code: "invalid-template", Description: "a template which cannot be parsed or executed"},
+	{Code: "invalid-project-link", Description: "a .Project link to a file which does not exist or is outside of the project"},
+}
+
+// Lint loads all tags and templates and returns all found diagnostics.
+// In addition to the warnings of Load, it checks the files linked by .Project.
+// Invalid templates are returned as diagnostics as well, but the templates are not checked any further then.
+func (a *AtWhy) Lint() ([]diagnostic.Diagnostic, error) {
+	snapshot, err := a.Snapshot()
+	var d diagnostic.Diagnostic
+	if errors.As(err, &d) {
+		a.Diagnostics.Report(d)
+		return a.Diagnostics.Diagnostics(), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, t := range snapshot.Templates {
+		err := a.lintProjectLinks(t)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return a.Diagnostics.Diagnostics(), nil
+}
+
+// lintProjectLinks executes the template and reports all links to project files which do not exist.
+func (a *AtWhy) lintProjectLinks(t mdTemplate.Markdown) error {
+	var links []string
+	t.SourceLink = nil
+	t.ProjectLinks = func(file string) {
+		links = append(links, file)
+	}
+
+	err := t.Execute(io.Discard)
+	if err != nil {
+		a.Diagnostics.Report(diagnostic.Diagnostic{
+			Severity: diagnostic.SeverityError,
+			Code:     "invalid-template",
+			File:     t.File(),
+			Message:  err.Error(),
+			Err:      err,
+		})
+		return nil
+	}
+
+	checked := make(map[string]bool)
+	for _, file := range links {
+		if checked[file] {
+			continue
+		}
+		checked[file] = true
+
+		cleaned := path.Clean(filepath.ToSlash(file))
+		if cleaned == ".." || strings.HasPrefix(cleaned, "../") || path.IsAbs(cleaned) {
+			a.Diagnostics.Report(diagnostic.Diagnostic{
+				Severity: diagnostic.SeverityWarning,
+				Code:     "invalid-project-link",
+				File:     t.File(),
+				Message:  "the linked file " + file + " is outside of the project",
+			})
+			continue
+		}
+
+		_, err := a.projectFS.Stat(cleaned)
+		if errors.Is(err, os.ErrNotExist) {
+			a.Diagnostics.Report(diagnostic.Diagnostic{
+				Severity: diagnostic.SeverityWarning,
+				Code:     "invalid-project-link",
+				File:     t.File(),
+				Message:  "the linked file " + file + " does not exist",
+			})
+		} else if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package core_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/core"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/diagnostic"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/finder"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/generator"
+	"github.com/stretchr/testify/assert"
+)
+
+func testLint(t *testing.T, files map[string]string) []diagnostic.Diagnostic {
+	projectPath := t.TempDir()
+	for name, content := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Join(projectPath, filepath.Dir(name)), 0775))
+		assert.NoError(t, os.WriteFile(filepath.Join(projectPath, name), []byte(content), 0664))
+	}
+
+	atwhy, err := core.New(generator.Markdown{}, projectPath, "/", "templates", nil, map[string]finder.CommentConfig{
+		".go": {LineComment: []string{"//"}},
+	}, core.Options{MissingSeverity: diagnostic.SeverityWarning})
+	assert.NoError(t, err)
+
+	diagnostics, err := atwhy.Lint()
+	assert.NoError(t, err)
+	return diagnostics
+}
+
+func TestAtWhy_Lint(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []string
+	}{
+		{
+			name: "no problems",
+			files: map[string]string{
+				"main.go":                 "// @WHY used\n// used\npackage main\n",
+				"docs/guide.md":           "# Guide\n",
+				"templates/README.tpl.md": "{{ .Tag.used }} [guide]({{ .Project \"docs/guide.md\" }}) [docs]({{ .Project \"docs\" }})",
+			},
+			want: []string{},
+		},
+		{
+			name: "tags",
+			files: map[string]string{
+				"main.go":                 "// @WHY used\n// used\n\n// @WHY used\n// again\n\n// @WHY unused\n// unused\n\n// @WHY empty\n\n// @WHY Invalid\n\n// @WHY CODE code\npackage main\n",
+				"templates/README.tpl.md": "{{ .Tag.used }} {{ .Tag.empty }} {{ .Tag.code }} {{ .Tag.missing }}",
+			},
+			want: []string{
+				"main.go:4: warning: the placeholder used is already used at main.go:1 (prefix it with + in all places to combine them)",
+				"main.go:7: warning: the tag unused is not used by any template",
+				"main.go:10:4: warning: the @WHY empty has no content",
+				"main.go:12:4: warning: found a @WHY which doesn't match the required format: @WHY Invalid",
+				"main.go:14:4: warning: the @WHY CODE code is not closed by a @WHY CODE_END, it includes the rest of the file",
+				"templates/README.tpl.md:1:57: warning: the tag missing does not exist",
+			},
+		},
+		{
+			name: "templates",
+			files: map[string]string{
+				"main.go":                 "// @WHY used\n// used\npackage main\n",
+				"templates/README.tpl.md": "---\ntitel: Readme\n---\n{{ .Tag.used }} [missing]({{ .Project \"nope.md\" }}) [outside]({{ .Project \"../other\" }})",
+			},
+			want: []string{
+				"templates/README.tpl.md: warning: the linked file nope.md does not exist",
+				"templates/README.tpl.md: warning: the linked file ../other is outside of the project",
+				"templates/README.tpl.md:2: warning: unknown key \"titel\" in the template header",
+			},
+		},
+		{
+			name: "invalid template",
+			files: map[string]string{
+				"main.go":                 "// @WHY unused\n// unused\npackage main\n",
+				"templates/README.tpl.md": "{{ .Tag.used",
+			},
+			want: []string{
+				"templates/README.tpl.md:1: error: unclosed action",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, d := range testLint(t, tt.files) {
+				got = append(got, d.String())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
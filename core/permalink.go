@@ -0,0 +1,78 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/gitfs"
+)
+
+var ErrNoRepoName = errors.New("the source link uses {repo} but the git repository has no origin remote")
+
+// @WHY readme_source_link
+// Links to project files (LINK tags and `{{"{{ .Project \"file\" }}"}}`) point to the file relative
+// to the generated documentation by default. Set a source link pattern to use permalinks
+// of a specific commit instead, e.g. with `--source-link` or in the `atwhy.yaml`:
+// ```yaml
+// source-link: https://github.com/{repo}/blob/{commit}/{path}#L{line}
+// ```
+// * `{repo}` is the path of the `origin` remote, e.g. `owner/name`.
+// * `{commit}` is the commit of the local `.git` HEAD (or of `--ref`).
+// * `{path}` is the path of the file relative to the repository root.
+// * `{line}` and `{endLine}` are the first and last line of a LINK tag,
+//   e.g. `#L{line}-L{endLine}` for GitHub.
+//   Pass them to `.Project` as additional parameters: `{{"{{ .Project \"file\" 42 50 }}"}}`.
+//   If a link has no line, the `#` fragment containing them is removed.
+//
+// The source link is only used by the generated files. `atwhy serve` and `atwhy site` still link their own files.
+
+// sourceLink creates permalinks to the project files of one git revision.
+type sourceLink struct {
+	pattern  string
+	revision gitfs.Revision
+}
+
+// newSourceLink resolves the revision of the project ("HEAD" if the ref is empty)
+// for the given pattern.
+func newSourceLink(pattern string, projectPath string, ref string) (sourceLink, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	revision, err := gitfs.Resolve(projectPath, ref)
+	if err != nil {
+		return sourceLink{}, fmt.Errorf("source link: %w", err)
+	}
+
+	if strings.Contains(pattern, "{repo}") && revision.Repo == "" {
+		return sourceLink{}, ErrNoRepoName
+	}
+
+	return sourceLink{
+		pattern:  pattern,
+		revision: revision,
+	}, nil
+}
+
+// URL returns the link to the file, which is relative to the project.
+// A line of 0 means the whole file.
+// The fragment is removed in that case if it contains {line} or {endLine}.
+func (s sourceLink) URL(file string, line int, endLine int) string {
+	pattern := s.pattern
+	if i := strings.Index(pattern, "#"); line == 0 && i >= 0 && (strings.Contains(pattern[i:], "{line}") || strings.Contains(pattern[i:], "{endLine}")) {
+		pattern = pattern[:i]
+	}
+
+	file = path.Join(s.revision.Folder, cleanPath(file))
+
+	return strings.NewReplacer(
+		"{repo}", s.revision.Repo,
+		"{commit}", s.revision.Commit,
+		"{path}", escapePath(file),
+		"{line}", strconv.Itoa(line),
+		"{endLine}", strconv.Itoa(endLine),
+	).Replace(pattern)
+}
@@ -0,0 +1,75 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/gitfs"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_sourceLink_URL(t *testing.T) {
+	revision := gitfs.Revision{Commit: "abc123", Repo: "owner/name", Folder: "."}
+
+	tests := []struct {
+		name     string
+		pattern  string
+		revision gitfs.Revision
+		file     string
+		line     int
+		endLine  int
+		want     string
+	}{
+		{
+			name:     "all placeholders",
+			pattern:  "https://github.com/{repo}/blob/{commit}/{path}#L{line}",
+			revision: revision,
+			file:     "core/atwhy.go",
+			line:     42,
+			want:     "https://github.com/owner/name/blob/abc123/core/atwhy.go#L42",
+		},
+		{
+			name:     "line range",
+			pattern:  "https://github.com/{repo}/blob/{commit}/{path}#L{line}-L{endLine}",
+			revision: revision,
+			file:     "core/atwhy.go",
+			line:     42,
+			endLine:  50,
+			want:     "https://github.com/owner/name/blob/abc123/core/atwhy.go#L42-L50",
+		},
+		{
+			name:     "no line removes the fragment",
+			pattern:  "https://github.com/{repo}/blob/{commit}/{path}#L{line}",
+			revision: revision,
+			file:     "core/atwhy.go",
+			want:     "https://github.com/owner/name/blob/abc123/core/atwhy.go",
+		},
+		{
+			name:     "no line keeps other fragments",
+			pattern:  "https://git.example.com/{path}?at={commit}#source",
+			revision: revision,
+			file:     "main.go",
+			want:     "https://git.example.com/main.go?at=abc123#source",
+		},
+		{
+			name:     "project in a sub folder",
+			pattern:  "https://github.com/{repo}/blob/{commit}/{path}#L{line}",
+			revision: gitfs.Revision{Commit: "abc123", Repo: "owner/name", Folder: "sub/project"},
+			file:     "/main.go",
+			line:     1,
+			want:     "https://github.com/owner/name/blob/abc123/sub/project/main.go#L1",
+		},
+		{
+			name:     "escapes the path",
+			pattern:  "https://github.com/{repo}/blob/{commit}/{path}",
+			revision: revision,
+			file:     "docs/a file#1.md",
+			want:     "https://github.com/owner/name/blob/abc123/docs/a%20file%231.md",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := sourceLink{pattern: tt.pattern, revision: tt.revision}
+			assert.Equal(t, tt.want, s.URL(tt.file, tt.line, tt.endLine))
+		})
+	}
+}
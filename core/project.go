@@ -0,0 +1,115 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/loader"
+)
+
+// @WHY readme_usage1_serve_project_files
+// The server only serves project files which are not ignored by the `.atwhyignore` files.
+// Files and folders starting with a dot (e.g. `.env`) are not served unless `--allow-dotfiles` is passed.
+// The `.git` folder is never served and folders are not listed.
+// Pass `--only-referenced` to serve only the files which are linked by the pages (LINK tags and `.Project`).
+
+// projectHandler serves the project files.
+// The projectPathPrefix has to be stripped from the request before.
+func (a *AtWhy) projectHandler(options HandlerOptions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := cleanPath(r.URL.Path)
+
+		allowed, err := a.canServe(name, options)
+		if err != nil {
+			// TODO use a logger
+			fmt.Println(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !allowed {
+			http.NotFound(w, r)
+			return
+		}
+
+		file, err := a.projectFS.Open(name)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer file.Close()
+
+		info, err := file.Stat()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		http.ServeContent(w, r, name, info.ModTime(), file)
+	})
+}
+
+// canServe checks if the project file with the given clean path may be served.
+// Folders are never served.
+func (a *AtWhy) canServe(name string, options HandlerOptions) (bool, error) {
+	info, err := a.projectFS.Stat(name)
+	if err != nil || info.IsDir() {
+		return false, nil
+	}
+
+	if !options.AllowDotfiles {
+		for _, segment := range strings.Split(name, "/") {
+			if strings.HasPrefix(segment, ".") {
+				return false, nil
+			}
+		}
+	}
+
+	ignored, err := loader.Ignored(a.projectFS, name, false)
+	if err != nil || ignored {
+		return false, err
+	}
+
+	if !options.OnlyReferenced {
+		return true, nil
+	}
+
+	referenced, err := a.referencedFiles()
+	if err != nil {
+		return false, err
+	}
+
+	// Linked folders allow all files inside of them.
+	for folder := name; folder != "."; folder = path.Dir(folder) {
+		if referenced[folder] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// referencedFiles returns the clean paths of all project files linked by the pages.
+// They are cached together with the pages.
+func (a *AtWhy) referencedFiles() (map[string]bool, error) {
+	return a.pages.getReferenced(a.loadAndLog, collectReferencedFiles)
+}
+
+// collectReferencedFiles executes all pages to find the project files linked by them.
+func collectReferencedFiles(snapshot Snapshot) (map[string]bool, error) {
+	referenced := make(map[string]bool)
+	for _, page := range snapshot.Templates {
+		page.SourceLink = nil
+		page.ProjectLinks = func(file string) {
+			referenced[cleanPath(file)] = true
+		}
+
+		err := page.Execute(io.Discard)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return referenced, nil
+}
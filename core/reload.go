@@ -0,0 +1,204 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/loader"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/watcher"
+)
+
+// pageCache holds the loaded tags and pages until they get invalidated by a change
+// of any project file or template.
+type pageCache struct {
+	mutex    sync.Mutex
+	snapshot Snapshot
+	valid    bool
+
+	// referenced contains the project files linked by the pages of the snapshot.
+	// It is nil until it is needed the first time.
+	referenced map[string]bool
+
+	// disabled forces a reload on each access.
+	// It is used if changes cannot be detected anymore.
+	disabled bool
+}
+
+func (c *pageCache) disable() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.disabled = true
+	c.valid = false
+	c.snapshot = Snapshot{}
+	c.referenced = nil
+}
+
+func (c *pageCache) invalidate() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.valid = false
+	c.snapshot = Snapshot{}
+	c.referenced = nil
+}
+
+// get returns the cached Snapshot or loads it using the given load function
+// if it is not valid anymore.
+func (c *pageCache) get(load func() (Snapshot, error)) (Snapshot, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.getLocked(load)
+}
+
+// getReferenced returns the cached project files linked by the pages of the Snapshot.
+// They are collected only once for each Snapshot, as this executes all pages.
+func (c *pageCache) getReferenced(load func() (Snapshot, error), collect func(Snapshot) (map[string]bool, error)) (map[string]bool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	snapshot, err := c.getLocked(load)
+	if err != nil {
+		return nil, err
+	}
+	if c.valid && c.referenced != nil {
+		return c.referenced, nil
+	}
+
+	referenced, err := collect(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	if c.valid {
+		c.referenced = referenced
+	}
+	return referenced, nil
+}
+
+// getLocked is get without locking the mutex.
+func (c *pageCache) getLocked(load func() (Snapshot, error)) (Snapshot, error) {
+	if c.valid {
+		return c.snapshot, nil
+	}
+
+	snapshot, err := load()
+	if err != nil || c.disabled {
+		return snapshot, err
+	}
+
+	c.snapshot = snapshot
+	c.valid = true
+	c.referenced = nil
+	return snapshot, nil
+}
+
+// reloadBroker notifies all connected browsers that they should reload the page.
+type reloadBroker struct {
+	mutex   sync.Mutex
+	clients map[chan struct{}]struct{}
+
+	// done closes all connections, so that the server can shut down.
+	done <-chan struct{}
+}
+
+func newReloadBroker(done <-chan struct{}) *reloadBroker {
+	return &reloadBroker{
+		clients: make(map[chan struct{}]struct{}),
+		done:    done,
+	}
+}
+
+func (b *reloadBroker) subscribe() chan struct{} {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	// Buffered so that a notification is never lost, even if the client is
+	// currently busy. Several notifications get merged into one.
+	client := make(chan struct{}, 1)
+	b.clients[client] = struct{}{}
+	return client
+}
+
+func (b *reloadBroker) unsubscribe(client chan struct{}) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	delete(b.clients, client)
+}
+
+func (b *reloadBroker) notify() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for client := range b.clients {
+		select {
+		case client <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// ServeHTTP streams the reload events using server-sent events.
+func (b *reloadBroker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	client := b.subscribe()
+	defer b.unsubscribe(client)
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-b.done:
+			return
+		case <-client:
+			if _, err := fmt.Fprint(w, "event: reload\ndata: {}\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// watch the project and the templates for changes.
+// On each change the page cache gets invalidated and the
+// browsers get notified to reload the page.
+// It blocks until the context is done.
+func (a *AtWhy) watch(ctx context.Context, broker *reloadBroker) error {
+	onChange := func() {
+		a.pages.invalidate()
+		broker.notify()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, 2)
+	go func() {
+		errs <- watcher.Poll{
+			FS:         a.projectFS,
+			IgnoreFile: loader.IgnoreFile,
+		}.Watch(ctx, onChange)
+	}()
+	go func() {
+		// The templates are always loaded, even if they are ignored in the project.
+		errs <- watcher.Poll{
+			FS: a.templateFS,
+		}.Watch(ctx, onChange)
+	}()
+
+	// Stop both watchers as soon as one fails.
+	err := <-errs
+	cancel()
+	<-errs
+	return err
+}
@@ -0,0 +1,34 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_pageCache_getReferenced(t *testing.T) {
+	loads, collects := 0, 0
+	load := func() (Snapshot, error) {
+		loads++
+		return Snapshot{}, nil
+	}
+	collect := func(Snapshot) (map[string]bool, error) {
+		collects++
+		return map[string]bool{"main.go": true}, nil
+	}
+
+	cache := &pageCache{}
+	for i := 0; i < 3; i++ {
+		got, err := cache.getReferenced(load, collect)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]bool{"main.go": true}, got)
+	}
+	assert.Equal(t, 1, loads)
+	assert.Equal(t, 1, collects, "the pages are only executed once for each snapshot")
+
+	cache.invalidate()
+	_, err := cache.getReferenced(load, collect)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, loads)
+	assert.Equal(t, 2, collects, "the referenced files are collected again after a reload")
+}
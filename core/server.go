@@ -2,14 +2,17 @@ package core
 
 import (
 	"bytes"
+	"context"
 	"embed"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
-	"io/fs"
 	"net/http"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	mdTemplate "github.com/Tiffinger-Thiel-GmbH/atwhy/template"
 )
@@ -21,90 +24,295 @@ type Page = mdTemplate.Markdown
 //go:embed html
 var TemplateFS embed.FS
 
-const templateFile = "page.gohtml"
+// eventsPath is the path of the server-sent events which notify the
+// browser about changes, relative to the root of the site.
+const eventsPath = "_atwhy/events"
 
-func (a *AtWhy) initPageTemplate() error {
-	subFS, err := fs.Sub(TemplateFS, "html")
+// Defaults of the ServerOptions.
+const (
+	DefaultReadHeaderTimeout = 10 * time.Second
+	DefaultIdleTimeout       = 2 * time.Minute
+	DefaultShutdownTimeout   = 5 * time.Second
+)
+
+// buildPage renders the given page into the page layout.
+// basePath is the path of the site root (e.g. "/" or "../"), it is used for all links.
+func (a *AtWhy) buildPage(writer io.Writer, page Page, pages []Page, basePath string, liveReload bool) error {
+	data := a.pageData(pages, basePath, liveReload)
+	data.ID = page.ID
+	data.Title = page.Header.Meta.Title
+	data.Page = page
+	data.Nav = data.buildNav()
+
+	buf := bytes.NewBufferString("")
+	err := a.Generate(page, buf)
 	if err != nil {
 		return err
 	}
+	data.Body = template.HTML(buf.String())
 
-	a.pageTemplate, err = template.ParseFS(subFS, templateFile)
-	return err
+	return a.executeLayout(writer, pageFile, data)
 }
 
-func (a *AtWhy) buildPage(writer io.Writer, pageID string, pages []Page) error {
-	data := struct {
-		ID    string
-		Title string
-		Body  template.HTML
+// buildNotFound renders the 404 page.
+func (a *AtWhy) buildNotFound(writer io.Writer, pages []Page, basePath string, liveReload bool) error {
+	data := a.pageData(pages, basePath, liveReload)
+	data.Title = "Page not found"
+	data.Nav = data.buildNav()
+
+	return a.executeLayout(writer, notFoundFile, data)
+}
 
-		Pages []Page
-	}{
-		ID:    pageID,
-		Pages: pages,
+func (a *AtWhy) pageData(pages []Page, basePath string, liveReload bool) PageData {
+	data := PageData{
+		Pages:      pages,
+		BasePath:   basePath,
+		StaticPath: basePath + staticPath,
 	}
 
-	for _, page := range pages {
-		if page.ID == pageID {
-			buf := bytes.NewBufferString("")
-			err := a.Generate(page, buf)
-			if err != nil {
-				return err
-			}
-			data.Body = template.HTML(buf.String())
-			data.Title = page.Header.Meta.Title
-		}
+	if liveReload {
+		data.EventsURL = basePath + eventsPath
 	}
+	return data
+}
 
-	return a.pageTemplate.ExecuteTemplate(writer, templateFile, data)
+func (a *AtWhy) executeLayout(writer io.Writer, name string, data PageData) error {
+	layouts, err := a.parseLayouts()
+	if err != nil {
+		return err
+	}
 
+	return layouts.ExecuteTemplate(writer, name, data)
 }
 
-func (a *AtWhy) ListenAndServe(host string) error {
-	fileServer := http.StripPrefix(a.projectPathPrefix, http.FileServer(http.Dir(a.projectPath)))
+// loadAndLog loads the tags and pages and prints all diagnostics.
+func (a *AtWhy) loadAndLog() (Snapshot, error) {
+	snapshot, err := a.Snapshot()
+	for _, d := range a.Diagnostics.Diagnostics() {
+		// TODO use a logger
+		fmt.Println(d)
+	}
+	return snapshot, err
+}
 
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		// Fast path: no need to generate everything if no html is requested.
-		if strings.HasPrefix(r.URL.Path, a.projectPathPrefix) {
-			fileServer.ServeHTTP(w, r)
-			return
-		}
+// HandlerOptions configures the http.Handler created by AtWhy.Handler.
+type HandlerOptions struct {
+	// BasePath is the url path at which the handler is mounted, e.g. "/docs/".
+	// The handler strips it from the requests itself.
+	// Default is "/".
+	BasePath string
 
-		path := r.URL.Path
-		if strings.HasSuffix(path, "/") {
-			path = path + "index.html"
-		}
+	// LiveReload watches the project and the templates for changes
+	// and reloads the open pages automatically.
+	// Without it, the pages are generated again on each request to show the changes.
+	// It is ignored if the files are read from a git ref.
+	LiveReload bool
+
+	// AllowDotfiles serves project files and folders starting with a dot (e.g. ".env").
+	// Files ignored by the .atwhyignore files and the .git folder are never served.
+	AllowDotfiles bool
+
+	// OnlyReferenced serves only the project files which are linked by the pages,
+	// e.g. by LINK tags or .Project.
+	OnlyReferenced bool
+}
+
+// ServerOptions configures AtWhy.ListenAndServe.
+// The timeouts are the same as in http.Server.
+type ServerOptions struct {
+	HandlerOptions
+
+	// ReadHeaderTimeout defaults to DefaultReadHeaderTimeout.
+	ReadHeaderTimeout time.Duration
+
+	// ReadTimeout is disabled by default.
+	ReadTimeout time.Duration
+
+	// WriteTimeout is disabled by default.
+	// Note that it also closes the connection used for the live reload.
+	WriteTimeout time.Duration
+
+	// IdleTimeout defaults to DefaultIdleTimeout.
+	IdleTimeout time.Duration
+
+	// ShutdownTimeout is the time to wait for open requests after the context is done.
+	// Defaults to DefaultShutdownTimeout.
+	ShutdownTimeout time.Duration
+}
+
+// Handler creates an http.Handler which serves the documentation.
+// It uses its own mux, so several handlers can be used side by side.
+//
+// The project files are served at the projectPathPrefix passed to New.
+// It has to be a sub path like "/project/" to not hide the pages.
+//
+// All background work (e.g. watching the files for the live reload) stops when the ctx is done.
+func (a *AtWhy) Handler(ctx context.Context, options HandlerOptions) (http.Handler, error) {
+	return a.handler(ctx, options, &sync.WaitGroup{})
+}
+
+// handler creates the Handler and adds all background goroutines to the given WaitGroup.
+func (a *AtWhy) handler(ctx context.Context, options HandlerOptions, background *sync.WaitGroup) (http.Handler, error) {
+	basePath := "/" + strings.Trim(options.BasePath, "/") + "/"
+	basePath = strings.Replace(basePath, "//", "/", 1)
+
+	static, err := a.staticHandler()
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/"+staticPath, http.StripPrefix("/"+staticPath, static))
+
+	if a.projectPathPrefix != "/" {
+		mux.Handle(a.projectPathPrefix, http.StripPrefix(a.projectPathPrefix, a.projectHandler(options)))
+	}
+
+	// The files of a ref never change.
+	liveReload := options.LiveReload && a.ref == ""
+	if liveReload {
+		broker := newReloadBroker(ctx.Done())
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			if err := a.watch(ctx, broker); err != nil {
+				// TODO use a logger
+				fmt.Println("the file watcher stopped, falling back to reloading on each request:", err)
+				a.pages.disable()
+			}
+		}()
+
+		mux.Handle("/"+eventsPath, broker)
+	} else if a.ref == "" {
+		// Without the watcher, changes of the files cannot be detected.
+		a.pages.disable()
+	}
 
-		// For now always load the templates and tags to be able to reflect any change instantly.
-		// Maybe later a fs watcher could be used...
-		templates, err := a.Load()
-		if err != nil {
-			// TODO use a logger
-			fmt.Println(err)
+	mux.HandleFunc("/"+tagsPath, func(w http.ResponseWriter, r *http.Request) {
+		a.serveTags(w, r, basePath, liveReload)
+	})
+	mux.Handle("/"+apiPath, http.StripPrefix("/"+apiPath, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.serveAPI(w, r, basePath)
+	})))
+
+	mux.Handle("/"+sourcePath, http.StripPrefix("/"+sourcePath, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.serveSource(w, r, basePath, liveReload, options)
+	})))
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		a.servePage(w, r, basePath, liveReload)
+	})
+
+	prefix := strings.TrimSuffix(basePath, "/")
+	stripped := http.StripPrefix(prefix, mux)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if prefix != "" && r.URL.Path == prefix {
+			http.Redirect(w, r, basePath, http.StatusMovedPermanently)
 			return
 		}
+		stripped.ServeHTTP(w, r)
+	}), nil
+}
+
+// servePage generates the requested page.
+// The path of the request has to be relative to the basePath.
+func (a *AtWhy) servePage(w http.ResponseWriter, r *http.Request, basePath string, liveReload bool) {
+	path := r.URL.Path
+	if strings.HasSuffix(path, "/") {
+		path = path + "index.html"
+	}
+
+	// The templates and tags are only loaded again if any file has changed.
+	snapshot, err := a.pages.get(a.loadAndLog)
+	if err != nil {
+		// TODO use a logger
+		fmt.Println(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	templates := snapshot.Templates
+
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
 
-		// Only generate the requested file.
-		for _, t := range templates {
-			if filepath.Join(t.Path, t.Name+a.Generator.Ext()) == path[1:] ||
-				(t.Header.Server.Index && filepath.Join(t.Path, "index.html") == path[1:]) {
-				// Found something
-				w.Header().Set("Content-Type", "text/html; charset=UTF-8")
-
-				err = a.buildPage(w, t.ID, templates)
-				if err != nil {
-					// TODO use a logger
-					fmt.Println(err)
-					return
-				}
-				return
+	// Only generate the requested file.
+	for _, t := range templates {
+		if a.OutputFile(t) == path[1:] ||
+			(t.Header.Server.Index && filepath.Join(t.Path, "index.html") == path[1:]) {
+			// Found something
+			t.ProjectPathPrefix = basePath + strings.TrimPrefix(a.projectPathPrefix, "/")
+			t.SourceLink = a.viewerLink(basePath)
+
+			err = a.buildPage(w, t, templates, basePath, liveReload)
+			if err != nil {
+				// TODO use a logger
+				fmt.Println(err)
 			}
+			return
 		}
+	}
 
-		w.WriteHeader(404)
-	})
+	w.WriteHeader(http.StatusNotFound)
+	err = a.buildNotFound(w, templates, basePath, liveReload)
+	if err != nil {
+		// TODO use a logger
+		fmt.Println(err)
+	}
+}
+
+// ListenAndServe serves the documentation on the given host until the ctx is done.
+// Then it shuts the server down gracefully and waits until all background work has stopped.
+func (a *AtWhy) ListenAndServe(ctx context.Context, host string, options ServerOptions) error {
+	// Wait until the background work has stopped after the cancel.
+	var background sync.WaitGroup
+	defer background.Wait()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	handler, err := a.handler(ctx, options.HandlerOptions, &background)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Addr:              host,
+		Handler:           handler,
+		ReadHeaderTimeout: options.ReadHeaderTimeout,
+		ReadTimeout:       options.ReadTimeout,
+		WriteTimeout:      options.WriteTimeout,
+		IdleTimeout:       options.IdleTimeout,
+	}
+	if server.ReadHeaderTimeout == 0 {
+		server.ReadHeaderTimeout = DefaultReadHeaderTimeout
+	}
+	if server.IdleTimeout == 0 {
+		server.IdleTimeout = DefaultIdleTimeout
+	}
+
+	shutdownTimeout := options.ShutdownTimeout
+	if shutdownTimeout == 0 {
+		shutdownTimeout = DefaultShutdownTimeout
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		fmt.Printf("Starting server on %s\n", host)
+		errs <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancelShutdown()
 
-	fmt.Printf("Starting server on %s\n", host)
-	return http.ListenAndServe(host, nil)
+	err = server.Shutdown(shutdownCtx)
+	if serveErr := <-errs; !errors.Is(serveErr, http.ErrServerClosed) && err == nil {
+		err = serveErr
+	}
+	return err
 }
@@ -0,0 +1,323 @@
+package core_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/core"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/finder"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/generator"
+	"github.com/stretchr/testify/assert"
+)
+
+func testServeProject(t *testing.T) core.AtWhy {
+	projectPath := t.TempDir()
+	files := map[string]string{
+		"main.go":                   "// @WHY LINK main_link\npackage main\n",
+		"templates/README.tpl.md":   "---\nserver:\n  index: true\n---\n# Readme\n{{ .Tag.main_link }}\n[docs]({{ .Project \"docs\" }})\n",
+		"templates/api/tags.tpl.md": "# Api tags\n",
+		"docs/guide.md":             "# Guide\n",
+		"other.go":                  "package main\n",
+		"secret.txt":                "secret\n",
+		".env":                      "TOKEN=secret\n",
+		".atwhyignore":              "secret.txt\n",
+	}
+	for name, content := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Join(projectPath, filepath.Dir(name)), 0775))
+		assert.NoError(t, os.WriteFile(filepath.Join(projectPath, name), []byte(content), 0664))
+	}
+
+	atwhy, err := core.New(&generator.HTML{}, projectPath, "/project/", "templates", nil, map[string]finder.CommentConfig{
+		".go": {LineComment: []string{"//"}},
+	}, core.Options{})
+	assert.NoError(t, err)
+	return atwhy
+}
+
+func TestAtWhy_Handler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	atwhy := testServeProject(t)
+	docs, err := atwhy.Handler(ctx, core.HandlerOptions{BasePath: "/docs/", LiveReload: true})
+	assert.NoError(t, err)
+	root, err := atwhy.Handler(ctx, core.HandlerOptions{})
+	assert.NoError(t, err)
+
+	// Both handlers can be mounted side by side.
+	mux := http.NewServeMux()
+	mux.Handle("/docs/", docs)
+	mux.Handle("/docs", docs)
+	mux.Handle("/", root)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := server.Client()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	tests := []struct {
+		name         string
+		path         string
+		wantStatus   int
+		wantContains []string
+	}{
+		{
+			name:       "page in the base path",
+			path:       "/docs/README.html",
+			wantStatus: http.StatusOK,
+			wantContains: []string{
+				`href="/docs/_atwhy/static/atwhy.css"`,
+				`href="/docs/README.html"`,
+				`<a href="/docs/_atwhy/source/main.go?lines=1-1#L1">`,
+				`new EventSource("\/docs\/_atwhy\/events")`,
+			},
+		},
+		{
+			name:         "index",
+			path:         "/docs/",
+			wantStatus:   http.StatusOK,
+			wantContains: []string{"<h1>Readme</h1>"},
+		},
+		{
+			name:       "base path without slash",
+			path:       "/docs",
+			wantStatus: http.StatusMovedPermanently,
+		},
+		{
+			name:         "project file",
+			path:         "/docs/project/main.go",
+			wantStatus:   http.StatusOK,
+			wantContains: []string{"package main"},
+		},
+		{
+			name:       "source viewer",
+			path:       "/docs/_atwhy/source/main.go?lines=1-1",
+			wantStatus: http.StatusOK,
+			wantContains: []string{
+				"<code>main.go</code>",
+				`<a href="/docs/project/main.go">Raw</a>`,
+				`<span class="line hl"><span class="ln" id="L1">`,
+				`<span class="kn">package</span>`,
+			},
+		},
+		{
+			name:         "missing source file",
+			path:         "/docs/_atwhy/source/nope.go",
+			wantStatus:   http.StatusNotFound,
+			wantContains: []string{"Page not found"},
+		},
+		{
+			name:       "tag explorer",
+			path:       "/docs/_atwhy/tags",
+			wantStatus: http.StatusOK,
+			wantContains: []string{
+				`<tr data-type="LINK" data-unused="false">`,
+				`<td><code>main_link</code></td>`,
+				`<a href="/docs/_atwhy/source/main.go?lines=1-1#L1">main.go:1</a>`,
+				`templates/README.tpl.md`,
+			},
+		},
+		{
+			name:       "api tags",
+			path:       "/docs/_atwhy/api/tags",
+			wantStatus: http.StatusOK,
+			wantContains: []string{
+				`"placeholder":"main_link"`,
+				`"templates":["templates/README.tpl.md"]`,
+				`"unused":false`,
+				`"link":"/docs/_atwhy/source/main.go?lines=1-1#L1"`,
+			},
+		},
+		{
+			name:       "api pages",
+			path:       "/docs/_atwhy/api/pages",
+			wantStatus: http.StatusOK,
+			wantContains: []string{
+				`"title":"README"`,
+				`"template":"templates/README.tpl.md"`,
+				`"output":"README.html"`,
+				`"link":"/docs/README.html"`,
+			},
+		},
+		{
+			name:         "api diagnostics",
+			path:         "/docs/_atwhy/api/diagnostics",
+			wantStatus:   http.StatusOK,
+			wantContains: []string{"[]"},
+		},
+		{
+			name:         "pages in an api folder",
+			path:         "/docs/api/tags.html",
+			wantStatus:   http.StatusOK,
+			wantContains: []string{"Api tags"},
+		},
+		{
+			name:       "unknown api",
+			path:       "/docs/_atwhy/api/nope",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:         "static file",
+			path:         "/docs/_atwhy/static/atwhy.css",
+			wantStatus:   http.StatusOK,
+			wantContains: []string{".navbar"},
+		},
+		{
+			name:         "missing page",
+			path:         "/docs/nope.html",
+			wantStatus:   http.StatusNotFound,
+			wantContains: []string{"Page not found"},
+		},
+		{
+			name:       "second handler without live reload",
+			path:       "/README.html",
+			wantStatus: http.StatusOK,
+			wantContains: []string{
+				`<a href="/_atwhy/source/main.go?lines=1-1#L1">`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := client.Get(server.URL + tt.path)
+			assert.NoError(t, err)
+			defer res.Body.Close()
+
+			body, err := io.ReadAll(res.Body)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+			for _, want := range tt.wantContains {
+				assert.Contains(t, string(body), want)
+			}
+		})
+	}
+
+	t.Run("events are not available without live reload", func(t *testing.T) {
+		res, err := client.Get(server.URL + "/_atwhy/events")
+		assert.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+}
+
+func TestAtWhy_Handler_withoutLiveReload(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	projectPath := t.TempDir()
+	template := filepath.Join(projectPath, "templates", "README.tpl.md")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(template), 0775))
+	assert.NoError(t, os.WriteFile(template, []byte("# Readme\nfirst version\n"), 0664))
+
+	atwhy, err := core.New(&generator.HTML{}, projectPath, "/project/", "templates", nil, nil, core.Options{})
+	assert.NoError(t, err)
+	handler, err := atwhy.Handler(ctx, core.HandlerOptions{})
+	assert.NoError(t, err)
+
+	get := func() string {
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/README.html", nil))
+		assert.Equal(t, http.StatusOK, res.Code)
+		return res.Body.String()
+	}
+
+	assert.Contains(t, get(), "first version")
+
+	// Without a watcher the changes are shown on the next request.
+	assert.NoError(t, os.WriteFile(template, []byte("# Readme\nsecond version\n"), 0664))
+	assert.Contains(t, get(), "second version")
+}
+
+func TestAtWhy_Handler_projectFiles(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	atwhy := testServeProject(t)
+
+	tests := []struct {
+		name    string
+		options core.HandlerOptions
+		// wantFiles maps the paths to the expected status codes.
+		wantFiles map[string]int
+	}{
+		{
+			name: "default",
+			wantFiles: map[string]int{
+				"/project/main.go":             http.StatusOK,
+				"/project/other.go":            http.StatusOK,
+				"/project/docs/guide.md":       http.StatusOK,
+				"/project/docs/":               http.StatusNotFound,
+				"/project/secret.txt":          http.StatusNotFound,
+				"/project/.env":                http.StatusNotFound,
+				"/project/.atwhyignore":        http.StatusNotFound,
+				"/_atwhy/source/main.go":       http.StatusOK,
+				"/_atwhy/source/secret.txt":    http.StatusNotFound,
+				"/_atwhy/source/.env":          http.StatusNotFound,
+				"/_atwhy/source/docs/guide.md": http.StatusOK,
+			},
+		},
+		{
+			name:    "dotfiles",
+			options: core.HandlerOptions{AllowDotfiles: true},
+			wantFiles: map[string]int{
+				"/project/.env":       http.StatusOK,
+				"/project/secret.txt": http.StatusNotFound,
+				"/_atwhy/source/.env": http.StatusOK,
+			},
+		},
+		{
+			name:    "only referenced",
+			options: core.HandlerOptions{OnlyReferenced: true},
+			wantFiles: map[string]int{
+				"/project/main.go":                 http.StatusOK,
+				"/project/docs/guide.md":           http.StatusOK,
+				"/project/other.go":                http.StatusNotFound,
+				"/_atwhy/source/main.go":           http.StatusOK,
+				"/_atwhy/source/other.go":          http.StatusNotFound,
+				"/project/templates/README.tpl.md": http.StatusNotFound,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, err := atwhy.Handler(ctx, tt.options)
+			assert.NoError(t, err)
+
+			for path, want := range tt.wantFiles {
+				res := httptest.NewRecorder()
+				handler.ServeHTTP(res, httptest.NewRequest(http.MethodGet, path, nil))
+				assert.Equal(t, want, res.Code, path)
+			}
+		})
+	}
+}
+
+func TestAtWhy_ListenAndServe(t *testing.T) {
+	atwhy := testServeProject(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		errs <- atwhy.ListenAndServe(ctx, "localhost:0", core.ServerOptions{
+			HandlerOptions:  core.HandlerOptions{LiveReload: true},
+			ShutdownTimeout: time.Second,
+		})
+	}()
+
+	cancel()
+	select {
+	case err := <-errs:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the server did not shut down")
+	}
+}
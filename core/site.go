@@ -0,0 +1,168 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/diagnostic"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/loader"
+	"github.com/spf13/afero"
+)
+
+// SiteProjectFolder is the folder of a static site which contains the
+// project files linked by the pages.
+const SiteProjectFolder = "project"
+
+// BuildSite writes all pages as a static html site using the page layout of the server.
+// The links to project files are rewritten to copies inside of the SiteProjectFolder.
+// Problems with the linked files are reported to the Diagnostics.
+func (a *AtWhy) BuildSite(outputFS afero.Fs, pages []Page) error {
+	err := outputFS.MkdirAll(".", 0775)
+	if err != nil {
+		return err
+	}
+
+	// The site is inside of the project in most cases.
+	// It contains copies of project files which must not be scanned for tags again.
+	// An existing ignore file is kept, e.g. if the site is written into the project root.
+	ignoreExists, err := afero.Exists(outputFS, loader.IgnoreFile)
+	if err != nil {
+		return err
+	}
+	if !ignoreExists {
+		err = afero.WriteFile(outputFS, loader.IgnoreFile, []byte("*\n"), 0664)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = a.writeStaticFiles(outputFS)
+	if err != nil {
+		return err
+	}
+
+	linked := make(map[string]bool)
+
+	for _, page := range pages {
+		// The path from the folder of the page back to the site root.
+		basePath := ""
+		if page.Path != "." {
+			basePath = strings.Repeat("../", len(strings.Split(filepath.ToSlash(page.Path), "/")))
+		}
+
+		page.ProjectPathPrefix = basePath + SiteProjectFolder
+		page.SourceLink = nil
+		page.ProjectLinks = func(file string) {
+			linked[file] = true
+		}
+
+		files := []string{a.OutputFile(page)}
+		if page.Header.Server.Index {
+			files = append(files, filepath.Join(page.Path, "index.html"))
+		}
+
+		err := outputFS.MkdirAll(page.Path, 0775)
+		if err != nil {
+			return err
+		}
+
+		for _, file := range files {
+			err := a.writeSitePage(outputFS, file, page, pages, basePath)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Static hosts use it for all missing pages, so relative links may not work in it
+	// if the site is not hosted at the root of the domain.
+	notFound, err := outputFS.Create("404.html")
+	if err != nil {
+		return err
+	}
+	err = a.buildNotFound(notFound, pages, "", false)
+	notFound.Close()
+	if err != nil {
+		return err
+	}
+
+	// Copy in a defined order to get reproducible results.
+	var files []string
+	for file := range linked {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		err := a.copyProjectFile(outputFS, file)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *AtWhy) writeSitePage(outputFS afero.Fs, file string, page Page, pages []Page, basePath string) error {
+	out, err := outputFS.Create(file)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	return a.buildPage(out, page, pages, basePath, false)
+}
+
+// copyProjectFile copies a file linked by .Project into the SiteProjectFolder.
+// Only regular files are copied, everything else is reported as warning.
+func (a *AtWhy) copyProjectFile(outputFS afero.Fs, file string) error {
+	cleaned := path.Clean(filepath.ToSlash(file))
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") || path.IsAbs(cleaned) {
+		a.Diagnostics.Report(diagnostic.Diagnostic{
+			Severity: diagnostic.SeverityWarning,
+			Code:     "invalid-project-link",
+			File:     file,
+			Message:  "the linked file is outside of the project and is not copied into the site",
+		})
+		return nil
+	}
+
+	info, err := a.projectFS.Stat(cleaned)
+	if err != nil {
+		return fmt.Errorf("the linked project file %v can not be copied into the site: %w", file, err)
+	}
+	if !info.Mode().IsRegular() {
+		a.Diagnostics.Report(diagnostic.Diagnostic{
+			Severity: diagnostic.SeverityWarning,
+			Code:     "invalid-project-link",
+			File:     cleaned,
+			Message:  "only files can be copied into the site, the link will not work",
+		})
+		return nil
+	}
+
+	target := path.Join(SiteProjectFolder, cleaned)
+	err = outputFS.MkdirAll(path.Dir(target), 0775)
+	if err != nil {
+		return err
+	}
+
+	src, err := a.projectFS.Open(cleaned)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := outputFS.Create(target)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	_, err = io.Copy(dst, src)
+	return err
+}
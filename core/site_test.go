@@ -0,0 +1,130 @@
+package core_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/core"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/diagnostic"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/finder"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/generator"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAtWhy_BuildSite(t *testing.T) {
+	projectPath := t.TempDir()
+	files := map[string]string{
+		"main.go":                         "// @WHY LINK main_link\npackage main\n",
+		"img/picture.png":                 "png",
+		"templates/README.tpl.md":         "---\nserver:\n  index: true\n---\n# Readme\n{{ .Tag.main_link }}\n",
+		"templates/docu/sub/Docu.tpl.md":  "# Docu\n![picture]({{ .Project \"img/picture.png\" }}) [folder]({{ .Project \"img\" }})\n",
+		"templates/docu/sub/Other.tpl.md": "# Other\n",
+	}
+	for name, content := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Join(projectPath, filepath.Dir(name)), 0775))
+		assert.NoError(t, os.WriteFile(filepath.Join(projectPath, name), []byte(content), 0664))
+	}
+
+	atwhy, err := core.New(&generator.HTML{}, projectPath, "/", "templates", nil, map[string]finder.CommentConfig{
+		".go": {LineComment: []string{"//"}},
+	}, core.Options{})
+	assert.NoError(t, err)
+
+	pages, err := atwhy.Load()
+	assert.NoError(t, err)
+
+	outputFS := afero.NewMemMapFs()
+	assert.NoError(t, atwhy.BuildSite(outputFS, pages))
+
+	readFile := func(name string) string {
+		content, err := afero.ReadFile(outputFS, name)
+		assert.NoError(t, err)
+		return string(content)
+	}
+
+	t.Run("writes all pages and the index", func(t *testing.T) {
+		readme := readFile("README.html")
+		assert.Equal(t, readme, readFile("index.html"))
+		assert.Contains(t, readme, `<a href="project/main.go">main.go:1</a>`)
+		assert.Contains(t, readme, `href="README.html"`)
+		assert.Contains(t, readme, `href="docu/sub/Docu.html"`)
+		assert.Contains(t, readFile("404.html"), "Page not found")
+	})
+
+	t.Run("uses relative links", func(t *testing.T) {
+		docu := readFile("docu/sub/Docu.html")
+		assert.Contains(t, docu, `<img src="../../project/img/picture.png" alt="picture">`)
+		assert.Contains(t, docu, `href="../../README.html"`)
+		assert.Contains(t, docu, `href="../../docu/sub/Other.html"`)
+		assert.Contains(t, docu, `href="../../_atwhy/static/atwhy.css"`)
+
+		// The navigation shows the folders of the pages.
+		assert.Contains(t, docu, `<summary class="nav-item nav-link active">sub</summary>`)
+		assert.Contains(t, docu, `<a class="nav-item nav-link active" href="../../docu/sub/Docu.html">Docu</a>`)
+
+		exists, err := afero.Exists(outputFS, "docu/sub/index.html")
+		assert.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("copies the linked files", func(t *testing.T) {
+		assert.Equal(t, "png", readFile("project/img/picture.png"))
+		assert.Equal(t, files["main.go"], readFile("project/main.go"))
+		assert.Equal(t, "*\n", readFile(".atwhyignore"))
+		assert.NotEmpty(t, readFile("_atwhy/static/atwhy.css"))
+	})
+
+	t.Run("reports links to folders", func(t *testing.T) {
+		assert.Equal(t, []diagnostic.Diagnostic{
+			{
+				Severity: diagnostic.SeverityWarning,
+				Code:     "invalid-project-link",
+				File:     "img",
+				Message:  "only files can be copied into the site, the link will not work",
+			},
+		}, atwhy.Diagnostics.Diagnostics())
+	})
+}
+
+func TestAtWhy_BuildSite_newFolder(t *testing.T) {
+	projectPath := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(projectPath, "templates"), 0775))
+	assert.NoError(t, os.WriteFile(filepath.Join(projectPath, "templates", "README.tpl.md"), []byte("# Readme\n"), 0664))
+
+	atwhy, err := core.New(&generator.HTML{}, projectPath, "/", "templates", nil, nil, core.Options{})
+	assert.NoError(t, err)
+
+	pages, err := atwhy.Load()
+	assert.NoError(t, err)
+
+	// The output folder does not exist yet.
+	outputFS := afero.NewBasePathFs(afero.NewOsFs(), filepath.Join(projectPath, "site"))
+	assert.NoError(t, atwhy.BuildSite(outputFS, pages))
+
+	assert.FileExists(t, filepath.Join(projectPath, "site", ".atwhyignore"))
+	assert.FileExists(t, filepath.Join(projectPath, "site", "README.html"))
+}
+
+func TestAtWhy_BuildSite_existingIgnoreFile(t *testing.T) {
+	projectPath := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(projectPath, "templates"), 0775))
+	assert.NoError(t, os.WriteFile(filepath.Join(projectPath, "templates", "README.tpl.md"), []byte("# Readme\n"), 0664))
+	assert.NoError(t, os.WriteFile(filepath.Join(projectPath, ".atwhyignore"), []byte("node_modules\n"), 0664))
+
+	atwhy, err := core.New(&generator.HTML{}, projectPath, "/", "templates", nil, nil, core.Options{})
+	assert.NoError(t, err)
+
+	pages, err := atwhy.Load()
+	assert.NoError(t, err)
+
+	// The site is written into the project root.
+	outputFS := afero.NewBasePathFs(afero.NewOsFs(), projectPath)
+	assert.NoError(t, atwhy.BuildSite(outputFS, pages))
+
+	content, err := os.ReadFile(filepath.Join(projectPath, ".atwhyignore"))
+	assert.NoError(t, err)
+	assert.Equal(t, "node_modules\n", string(content))
+	assert.FileExists(t, filepath.Join(projectPath, "README.html"))
+}
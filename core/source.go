@@ -0,0 +1,170 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/generator"
+	mdTemplate "github.com/Tiffinger-Thiel-GmbH/atwhy/template"
+	"github.com/spf13/afero"
+)
+
+// sourcePath is the path of the source viewer, relative to the root of the site.
+// It is followed by the path of the project file.
+const sourcePath = "_atwhy/source/"
+
+// linesParam is the query parameter of the source viewer with the highlighted lines, e.g. "?lines=5-9".
+const linesParam = "lines"
+
+// SourceData is passed to the source layout of the theme.
+type SourceData struct {
+	// Path is the path of the file relative to the project.
+	Path string
+
+	// RawLink is the link to the file itself.
+	// It is empty if the project files are not served.
+	RawLink string
+}
+
+// @WHY readme_usage1_serve_source
+// LINK tags open a source viewer in serve mode. It shows the file with syntax highlighting
+// and line numbers, scrolls to the tag and highlights all of its lines.
+// Each line can be linked with an anchor like `#L42`.
+
+// viewerLink creates the links of .Project in serve mode.
+// Links with lines open the source viewer, all others the project file itself.
+func (a *AtWhy) viewerLink(basePath string) mdTemplate.SourceLinkFunc {
+	return func(file string, line int, endLine int) string {
+		if line == 0 {
+			return a.rawLink(basePath, file)
+		}
+
+		if endLine < line {
+			endLine = line
+		}
+		return basePath + sourcePath + escapePath(cleanPath(file)) +
+			"?" + linesParam + "=" + strconv.Itoa(line) + "-" + strconv.Itoa(endLine) +
+			"#" + generator.LineAnchorPrefix + strconv.Itoa(line)
+	}
+}
+
+// rawLink returns the link to the project file served at the projectPathPrefix.
+func (a *AtWhy) rawLink(basePath string, file string) string {
+	return path.Join(basePath+strings.TrimPrefix(a.projectPathPrefix, "/"), file)
+}
+
+// serveSource renders the project file of the request with the source layout.
+// The path of the request has to be relative to the sourcePath.
+// Only files which can be served by the projectHandler are shown.
+func (a *AtWhy) serveSource(w http.ResponseWriter, r *http.Request, basePath string, liveReload bool, options HandlerOptions) {
+	name := cleanPath(r.URL.Path)
+
+	snapshot, err := a.pages.get(a.loadAndLog)
+	if err != nil {
+		// TODO use a logger
+		fmt.Println(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	pages := snapshot.Templates
+
+	allowed, err := a.canServe(name, options)
+	if err != nil {
+		// TODO use a logger
+		fmt.Println(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+		w.WriteHeader(http.StatusNotFound)
+		err = a.buildNotFound(w, pages, basePath, liveReload)
+		if err != nil {
+			// TODO use a logger
+			fmt.Println(err)
+		}
+		return
+	}
+
+	info, err := a.projectFS.Stat(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	content, err := afero.ReadFile(a.projectFS, name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Binary files (e.g. pictures) cannot be shown as source.
+	if !utf8.Valid(content) || bytes.IndexByte(content, 0) >= 0 {
+		http.ServeContent(w, r, name, info.ModTime(), bytes.NewReader(content))
+		return
+	}
+
+	body := &bytes.Buffer{}
+	err = generator.HighlightSource(body, name, string(content), parseLines(r.URL.Query().Get(linesParam)))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data := a.pageData(pages, basePath, liveReload)
+	data.Title = name
+	data.Body = template.HTML(body.String())
+	data.Source = &SourceData{Path: name}
+	if a.projectPathPrefix != "/" {
+		data.Source.RawLink = a.rawLink(basePath, name)
+	}
+	data.Nav = data.buildNav()
+
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	err = a.executeLayout(w, sourceFile, data)
+	if err != nil {
+		// TODO use a logger
+		fmt.Println(err)
+	}
+}
+
+// parseLines parses a range of lines like "5-9" or a single line like "5".
+// Invalid values are ignored.
+func parseLines(value string) [][2]int {
+	first, last := value, value
+	if i := strings.Index(value, "-"); i >= 0 {
+		first, last = value[:i], value[i+1:]
+	}
+
+	start, err := strconv.Atoi(first)
+	if err != nil || start < 1 {
+		return nil
+	}
+	end, err := strconv.Atoi(last)
+	if err != nil || end < start {
+		return nil
+	}
+	return [][2]int{{start, end}}
+}
+
+// cleanPath converts the file into a clean path relative to the project.
+func cleanPath(file string) string {
+	return strings.TrimPrefix(path.Clean("/"+file), "/")
+}
+
+// escapePath escapes each segment of the path for the use in urls.
+func escapePath(file string) string {
+	segments := strings.Split(file, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
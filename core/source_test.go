@@ -0,0 +1,26 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseLines(t *testing.T) {
+	tests := []struct {
+		value string
+		want  [][2]int
+	}{
+		{value: "5-9", want: [][2]int{{5, 9}}},
+		{value: "5", want: [][2]int{{5, 5}}},
+		{value: "", want: nil},
+		{value: "9-5", want: nil},
+		{value: "0-5", want: nil},
+		{value: "a-b", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseLines(tt.value))
+		})
+	}
+}
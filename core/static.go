@@ -0,0 +1,135 @@
+package core
+
+import (
+	"bytes"
+	"io/fs"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/generator"
+	"github.com/spf13/afero"
+)
+
+// staticPath is the path of the static files needed by the pages (css, js, ...),
+// relative to the root of the site.
+const staticPath = "_atwhy/static/"
+
+// highlightFile is the generated css for the syntax highlighting.
+// It is served together with the static files.
+const highlightFile = "highlight.css"
+
+// staticFiles returns all static files including the generated ones.
+func staticFiles() (map[string][]byte, error) {
+	files := make(map[string][]byte)
+
+	staticFS, err := fs.Sub(TemplateFS, "html/static")
+	if err != nil {
+		return nil, err
+	}
+
+	err = fs.WalkDir(staticFS, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		files[name], err = fs.ReadFile(staticFS, name)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	err = generator.WriteHighlightCSS(buf)
+	if err != nil {
+		return nil, err
+	}
+	files[highlightFile] = buf.Bytes()
+
+	return files, nil
+}
+
+// staticHandler serves the static files.
+// The files of the theme take precedence over the built-in ones.
+// The staticPath has to be stripped from the request before.
+func (a *AtWhy) staticHandler() (http.Handler, error) {
+	files, err := staticFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	// The built-in files never change while the server is running.
+	started := time.Now()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/")
+
+		content, info, err := a.themeStaticFile(name)
+		if err == nil {
+			http.ServeContent(w, r, name, info.ModTime(), bytes.NewReader(content))
+			return
+		}
+
+		content, ok := files[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		http.ServeContent(w, r, name, started, bytes.NewReader(content))
+	}), nil
+}
+
+// writeStaticFiles writes the static files into the staticPath of the given FS.
+// The files of the theme take precedence over the built-in ones.
+func (a *AtWhy) writeStaticFiles(outputFS afero.Fs) error {
+	files, err := staticFiles()
+	if err != nil {
+		return err
+	}
+
+	hasThemeFiles := false
+	if a.themeFS != nil {
+		hasThemeFiles, err = afero.DirExists(a.themeFS, themeStaticFolder)
+		if err != nil {
+			return err
+		}
+	}
+
+	if hasThemeFiles {
+		err := afero.Walk(a.themeFS, themeStaticFolder, func(name string, info fs.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+
+			relative, err := filepath.Rel(themeStaticFolder, name)
+			if err != nil {
+				return err
+			}
+
+			files[filepath.ToSlash(relative)], err = afero.ReadFile(a.themeFS, name)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for name, content := range files {
+		file := path.Join(staticPath, name)
+		err := outputFS.MkdirAll(path.Dir(file), 0775)
+		if err != nil {
+			return err
+		}
+
+		err = afero.WriteFile(outputFS, file, content, 0664)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
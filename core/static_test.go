@@ -0,0 +1,68 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_staticHandler(t *testing.T) {
+	handler, err := (&AtWhy{}).staticHandler()
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name            string
+		path            string
+		wantStatus      int
+		wantContentType string
+	}{
+		{
+			name:            "embedded file",
+			path:            "atwhy.css",
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/css; charset=utf-8",
+		},
+		{
+			name:            "generated highlighting",
+			path:            highlightFile,
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/css; charset=utf-8",
+		},
+		{
+			name:            "javascript",
+			path:            "atwhy.js",
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/javascript; charset=utf-8",
+		},
+		{
+			name:       "unknown file",
+			path:       "bootstrap.css",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/"+tt.path, nil))
+
+			assert.Equal(t, tt.wantStatus, recorder.Code)
+			if tt.wantContentType != "" {
+				assert.Equal(t, tt.wantContentType, recorder.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func Test_writeStaticFiles(t *testing.T) {
+	outputFS := afero.NewMemMapFs()
+	assert.NoError(t, (&AtWhy{}).writeStaticFiles(outputFS))
+
+	for _, file := range []string{"atwhy.css", "atwhy.js", highlightFile} {
+		exists, err := afero.Exists(outputFS, staticPath+file)
+		assert.NoError(t, err)
+		assert.True(t, exists, file)
+	}
+}
@@ -0,0 +1,49 @@
+package tag
+
+import "strings"
+
+// Accumulated combines several tags with the same placeholder into one.
+// It is used for tags with the + prefix (e.g. \@WHY +placeholder_name).
+// All methods except String use the first tag.
+type Accumulated []Tag
+
+func (a Accumulated) Type() Type {
+	return a[0].Type()
+}
+
+// String concatenates all tags using hard newlines.
+func (a Accumulated) String() string {
+	values := make([]string, len(a))
+	for i, t := range a {
+		values[i] = t.String()
+	}
+	return strings.Join(values, HardNewLine)
+}
+
+func (a Accumulated) Placeholder() string {
+	return a[0].Placeholder()
+}
+
+func (a Accumulated) File() string {
+	return a[0].File()
+}
+
+func (a Accumulated) Line() int {
+	return a[0].Line()
+}
+
+func (a Accumulated) Column() int {
+	return a[0].Column()
+}
+
+func (a Accumulated) EndLine() int {
+	return a[0].EndLine()
+}
+
+func (a Accumulated) EndColumn() int {
+	return a[0].EndColumn()
+}
+
+func (a Accumulated) Accumulate() bool {
+	return true
+}
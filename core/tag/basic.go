@@ -4,6 +4,8 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/diagnostic"
 )
 
 const (
@@ -14,6 +16,26 @@ type Basic struct {
 	tagType     Type
 	value       string
 	placeholder string
+	file        string
+	line        int
+	column      int
+	endLine     int
+	endColumn   int
+	accumulate  bool
+}
+
+func newBasic(input Raw, value string) Basic {
+	return Basic{
+		tagType:     input.Type,
+		placeholder: input.Placeholder,
+		value:       value,
+		file:        input.Filename,
+		line:        input.Line,
+		column:      input.Column,
+		endLine:     input.EndLine,
+		endColumn:   input.EndColumn,
+		accumulate:  input.Accumulate,
+	}
 }
 
 func (b Basic) Type() Type {
@@ -28,6 +50,30 @@ func (b Basic) Placeholder() string {
 	return b.placeholder
 }
 
+func (b Basic) File() string {
+	return b.file
+}
+
+func (b Basic) Line() int {
+	return b.line
+}
+
+func (b Basic) Column() int {
+	return b.column
+}
+
+func (b Basic) EndLine() int {
+	return b.endLine
+}
+
+func (b Basic) EndColumn() int {
+	return b.endColumn
+}
+
+func (b Basic) Accumulate() bool {
+	return b.accumulate
+}
+
 func textFactory(input Raw, isMarkdown bool) Basic {
 	// First remove windows line endings.
 	input.Value = strings.ReplaceAll(input.Value, "\r\n", "\n")
@@ -45,10 +91,18 @@ func textFactory(input Raw, isMarkdown bool) Basic {
 	}
 
 	body = strings.TrimRight(body, " \n")
-	return Basic{
-		tagType:     input.Type,
-		placeholder: input.Placeholder,
-		value:       body,
+	return newBasic(input, body)
+}
+
+// emptyWarning is returned together with tags which have no content.
+func emptyWarning(input Raw) error {
+	return diagnostic.Diagnostic{
+		Severity: diagnostic.SeverityWarning,
+		Code:     "empty-tag",
+		File:     input.Filename,
+		Line:     input.Line,
+		Column:   input.Column,
+		Message:  "the @WHY " + input.Placeholder + " has no content",
 	}
 }
 
@@ -62,12 +116,14 @@ func ProjectLink(input Raw) (Tag, error) {
 	escapedTitle := strings.ReplaceAll(input.Filename, "[", `\[`)
 	escapedTitle = strings.ReplaceAll(escapedTitle, "]", `\]`)
 
-	return Basic{
-		tagType:     input.Type,
-		placeholder: input.Placeholder,
-		// Insert the link-path as relative to be able to replace it in the final rendering based on the template path.
-		value: "[" + escapedTitle + ":" + strconv.Itoa(input.Line) + `]({{ .Project "` + escapedProjectFile + `" }})`,
-	}, nil
+	// Insert the link-path as relative to be able to replace it in the final rendering based on the template path.
+	// The lines are passed to be able to link them directly (e.g. in permalinks).
+	endLine := input.EndLine
+	if endLine < input.Line {
+		endLine = input.Line
+	}
+	line := strconv.Itoa(input.Line)
+	return newBasic(input, "["+escapedTitle+":"+line+`]({{ .Project "`+escapedProjectFile+`" `+line+" "+strconv.Itoa(endLine)+` }})`), nil
 }
 
 func Doc(input Raw) (Tag, error) {
@@ -76,6 +132,9 @@ func Doc(input Raw) (Tag, error) {
 	}
 
 	newTag := textFactory(input, true)
+	if newTag.value == "" {
+		return newTag, emptyWarning(input)
+	}
 
 	return newTag, nil
 }
@@ -86,8 +145,11 @@ func Code(input Raw) (Tag, error) {
 	}
 
 	newTag := textFactory(input, false)
+	if newTag.value == "" {
+		return newTag, emptyWarning(input)
+	}
 
-	codeType := filepath.Ext(input.Filename)[1:]
+	codeType := strings.TrimPrefix(filepath.Ext(input.Filename), ".")
 	newTag.value = "```" + codeType + "\n" + newTag.value + "\n```\n"
 
 	return newTag, nil
@@ -3,6 +3,7 @@ package tag
 import (
 	"testing"
 
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/diagnostic"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -24,6 +25,9 @@ func Test_textFactory(t *testing.T) {
 					Placeholder: "a_placeholder",
 					Filename:    "file",
 					Line:        5,
+					Column:      4,
+					EndLine:     6,
+					EndColumn:   8,
 					Value:       "header\nvalue",
 				},
 				isMarkdown: false,
@@ -32,6 +36,11 @@ func Test_textFactory(t *testing.T) {
 				tagType:     TypeDoc,
 				value:       "value",
 				placeholder: "a_placeholder",
+				file:        "file",
+				line:        5,
+				column:      4,
+				endLine:     6,
+				endColumn:   8,
 			},
 		},
 		{
@@ -50,6 +59,8 @@ func Test_textFactory(t *testing.T) {
 				tagType:     TypeDoc,
 				value:       "value\nlol",
 				placeholder: "a_placeholder",
+				file:        "file",
+				line:        5,
 			},
 		},
 		{
@@ -68,6 +79,8 @@ func Test_textFactory(t *testing.T) {
 				tagType:     TypeDoc,
 				value:       "",
 				placeholder: "a_placeholder",
+				file:        "file",
+				line:        5,
 			},
 		},
 		{
@@ -86,6 +99,8 @@ func Test_textFactory(t *testing.T) {
 				tagType:     TypeDoc,
 				value:       "foo\nbar",
 				placeholder: "a_placeholder",
+				file:        "file",
+				line:        5,
 			},
 		},
 		{
@@ -104,6 +119,8 @@ func Test_textFactory(t *testing.T) {
 				tagType:     TypeDoc,
 				value:       "foo   \nbar     \nbaz  \nbum", // Note that for now its ok to just have more spaces. They don't hurt...
 				placeholder: "a_placeholder",
+				file:        "file",
+				line:        5,
 			},
 		},
 	}
@@ -138,7 +155,9 @@ func TestProjectLink(t *testing.T) {
 			want: Basic{
 				tagType:     TypeLink,
 				placeholder: "a_placeholder",
-				value:       `[file.txt:5]({{ .Project "file.txt" }})`,
+				file:        "file.txt",
+				line:        5,
+				value:       `[file.txt:5]({{ .Project "file.txt" 5 5 }})`,
 			},
 			wantErr: assert.NoError,
 		},
@@ -168,7 +187,9 @@ func TestProjectLink(t *testing.T) {
 			want: Basic{
 				tagType:     TypeLink,
 				placeholder: "a_placeholder",
-				value:       `[fi"le.txt:5]({{ .Project "fi\"le.txt" }})`,
+				file:        `fi"le.txt`,
+				line:        5,
+				value:       `[fi"le.txt:5]({{ .Project "fi\"le.txt" 5 5 }})`,
 			},
 			wantErr: assert.NoError,
 		},
@@ -185,7 +206,9 @@ func TestProjectLink(t *testing.T) {
 			want: Basic{
 				tagType:     TypeLink,
 				placeholder: "a_placeholder",
-				value:       `[fi(l)\[e\].txt:5]({{ .Project "fi(l\)[e].txt" }})`,
+				file:        `fi(l)[e].txt`,
+				line:        5,
+				value:       `[fi(l)\[e\].txt:5]({{ .Project "fi(l\)[e].txt" 5 5 }})`,
 			},
 			wantErr: assert.NoError,
 		},
@@ -224,10 +247,40 @@ func TestDoc(t *testing.T) {
 			want: Basic{
 				tagType:     TypeDoc,
 				placeholder: "a_placeholder",
+				file:        "file.txt",
+				line:        5,
 				value:       "some  \ntext",
 			},
 			wantErr: assert.NoError,
 		},
+		{
+			name: "empty text returns a warning",
+			args: args{
+				input: Raw{
+					Type:        TypeDoc,
+					Placeholder: "a_placeholder",
+					Filename:    "file.txt",
+					Line:        5,
+					Value:       "header\n",
+				},
+			},
+			want: Basic{
+				tagType:     TypeDoc,
+				placeholder: "a_placeholder",
+				file:        "file.txt",
+				line:        5,
+				value:       "",
+			},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.Equal(t, diagnostic.Diagnostic{
+					Severity: diagnostic.SeverityWarning,
+					Code:     "empty-tag",
+					File:     "file.txt",
+					Line:     5,
+					Message:  "the @WHY a_placeholder has no content",
+				}, err)
+			},
+		},
 		{
 			name: "not a TypeDoc - should return nil, nil",
 			args: args{
@@ -277,10 +330,32 @@ func TestCode(t *testing.T) {
 			want: Basic{
 				tagType:     TypeCode,
 				placeholder: "a_placeholder",
+				file:        "file.txt",
+				line:        5,
 				value:       "```txt\nsome\ntext\n```\n",
 			},
 			wantErr: assert.NoError,
 		},
+		{
+			name: "file without extension",
+			args: args{
+				input: Raw{
+					Type:        TypeCode,
+					Placeholder: "a_placeholder",
+					Filename:    "Makefile",
+					Line:        5,
+					Value:       "header\nsome\ntext\n",
+				},
+			},
+			want: Basic{
+				tagType:     TypeCode,
+				placeholder: "a_placeholder",
+				file:        "Makefile",
+				line:        5,
+				value:       "```\nsome\ntext\n```\n",
+			},
+			wantErr: assert.NoError,
+		},
 		{
 			name: "not a TypeCode - should return nil, nil",
 			args: args{
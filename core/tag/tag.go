@@ -8,6 +8,12 @@ type Type string
 // * `\@WHY LINK <placeholder_name>` can be used to just add a link to the file where the tag is in.
 // * `\@WHY CODE <placeholder_name>` can be used to reference any code.
 //   It has to be closed by `\@WHY CODE_END`
+//
+// Each placeholder may only be used once in the whole project.
+// Duplicates are reported with both locations (use `--duplicates=error` to fail in that case).
+// If you really want to combine tags from several places into one placeholder,
+// prefix the placeholder with a `+` in all of them, e.g. `\@WHY +<placeholder_name>` or `\@WHY CODE +<placeholder_name>`.
+// They get concatenated ordered by the file path and line.
 
 var (
 	TypeDoc     Type = "DOC"
@@ -21,8 +27,18 @@ type Raw struct {
 	Type        Type   `json:"type,omitempty"`
 	Placeholder string `json:"placeholder,omitempty"`
 	Filename    string `json:"filename,omitempty"`
-	Line        int    `json:"line,omitempty"`
 	Value       string `json:"value,omitempty"`
+
+	// Line and Column are the 1-based position of the \@WHY.
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+
+	// EndLine and EndColumn are the 1-based position of the last character of the tag.
+	EndLine   int `json:"endLine,omitempty"`
+	EndColumn int `json:"endColumn,omitempty"`
+
+	// Accumulate is set if the placeholder was prefixed by a +.
+	Accumulate bool `json:"accumulate,omitempty"`
 }
 
 // Tag which was parsed from the code.
@@ -35,9 +51,28 @@ type Tag interface {
 	String() string
 
 	Placeholder() string
+
+	// File returns the path of the file containing the tag, relative to the project root.
+	File() string
+
+	// Line returns the 1-based line of the tag in the File.
+	Line() int
+
+	// Column returns the 1-based column of the \@WHY in the Line.
+	Column() int
+
+	// EndLine and EndColumn return the 1-based position of the last character of the tag.
+	EndLine() int
+	EndColumn() int
+
+	// Accumulate returns true if the tag should be combined with all other
+	// tags with the same placeholder instead of being reported as duplicate.
+	Accumulate() bool
 }
 
 // Factory describes a function which can convert a Raw tag into a normal Tag.
+// If the returned error is a diagnostic.Diagnostic with diagnostic.SeverityWarning,
+// the warning gets reported and the returned Tag is still used.
 // If the resulting Tag does not return nil on Tag.Children(), it means that the
 // children have been consumed and the next factory call should get a new slice.
 type Factory func(input Raw) (Tag, error)
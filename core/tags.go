@@ -0,0 +1,108 @@
+package core
+
+import (
+	"path/filepath"
+	"sort"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/core/tag"
+	mdTemplate "github.com/Tiffinger-Thiel-GmbH/atwhy/template"
+)
+
+// TagInfo describes a tag of the project and where it is used.
+type TagInfo struct {
+	Placeholder string   `json:"placeholder" yaml:"placeholder"`
+	Type        tag.Type `json:"type" yaml:"type"`
+
+	// File is the path relative to the project.
+	File string `json:"file" yaml:"file"`
+
+	// Line, Column, EndLine and EndColumn are 1-based.
+	Line      int `json:"line" yaml:"line"`
+	Column    int `json:"column" yaml:"column"`
+	EndLine   int `json:"endLine" yaml:"endLine"`
+	EndColumn int `json:"endColumn" yaml:"endColumn"`
+
+	// Value is the processed value of the tag before the templates are executed.
+	Value string `json:"value" yaml:"value"`
+
+	// Templates are the files of all templates which use the tag (also through their partials), relative to the project.
+	Templates []string `json:"templates" yaml:"templates"`
+
+	// Unused is true if no template uses the tag.
+	Unused bool `json:"unused" yaml:"unused"`
+
+	// Link is the link to the tag in the source viewer.
+	// It is only set by the server.
+	Link string `json:"link,omitempty" yaml:"link,omitempty"`
+}
+
+// PageInfo describes a page generated from a template.
+type PageInfo struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+
+	// Template is the path of the template relative to the project.
+	Template string `json:"template"`
+
+	// Output is the path of the generated file relative to the output folder.
+	Output string `json:"output"`
+
+	// Link is the link to the page.
+	// It is only set by the server.
+	Link string `json:"link,omitempty"`
+}
+
+// TagInfos describes all tags of the Snapshot.
+// They are sorted by their file and line.
+func TagInfos(snapshot Snapshot) []TagInfo {
+	references := mdTemplate.UsedReferences(snapshot.Templates)
+
+	infos := make([]TagInfo, 0, len(snapshot.Tags))
+	for _, t := range snapshot.Tags {
+		info := TagInfo{
+			Placeholder: t.Placeholder(),
+			Type:        t.Type(),
+			File:        t.File(),
+			Line:        t.Line(),
+			Column:      t.Column(),
+			EndLine:     t.EndLine(),
+			EndColumn:   t.EndColumn(),
+			Value:       t.String(),
+			Templates:   []string{},
+		}
+
+		templates := make(map[string]bool)
+		for _, r := range references {
+			if r.Matches(info.Placeholder) && !templates[r.Template] {
+				templates[r.Template] = true
+				info.Templates = append(info.Templates, r.Template)
+			}
+		}
+		sort.Strings(info.Templates)
+		info.Unused = len(info.Templates) == 0
+
+		infos = append(infos, info)
+	}
+
+	sort.SliceStable(infos, func(i, j int) bool {
+		if infos[i].File != infos[j].File {
+			return infos[i].File < infos[j].File
+		}
+		return infos[i].Line < infos[j].Line
+	})
+	return infos
+}
+
+// PageInfos describes all pages of the Snapshot in the order of the templates.
+func (a *AtWhy) PageInfos(snapshot Snapshot) []PageInfo {
+	infos := make([]PageInfo, 0, len(snapshot.Templates))
+	for _, t := range snapshot.Templates {
+		infos = append(infos, PageInfo{
+			ID:       t.ID,
+			Title:    t.Header.Meta.Title,
+			Template: t.File(),
+			Output:   filepath.ToSlash(a.OutputFile(t)),
+		})
+	}
+	return infos
+}
@@ -0,0 +1,69 @@
+package core_test
+
+import (
+	"testing"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/core"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/core/tag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTagInfos(t *testing.T) {
+	snapshot := testSnapshot(t, map[string]string{
+		"main.go":                 "// @WHY used\n// used\n\n// @WHY unused\n// unused\n\n// @WHY group_a\n// a\npackage main\n",
+		"b.go":                    "// @WHY LINK link\npackage main\n",
+		"templates/README.tpl.md": "{{ .Tag.used }} {{ .Tag.link }}",
+		"templates/Other.tpl.md":  "{{ .Tag.used }} {{ template \"footer\" . }}",
+		// The tags used by partials are attributed to the templates using them.
+		"templates/_partials/footer.tpl.md": "{{ .Group \"group_\" }}",
+	})
+
+	got := core.TagInfos(snapshot)
+	assert.Equal(t, []core.TagInfo{
+		{
+			Placeholder: "link",
+			Type:        tag.TypeLink,
+			File:        "b.go",
+			Line:        1,
+			Column:      4,
+			EndLine:     1,
+			EndColumn:   17,
+			Value:       `[b.go:1]({{ .Project "b.go" 1 1 }})`,
+			Templates:   []string{"templates/README.tpl.md"},
+		},
+		{
+			Placeholder: "used",
+			Type:        tag.TypeDoc,
+			File:        "main.go",
+			Line:        1,
+			Column:      4,
+			EndLine:     2,
+			EndColumn:   7,
+			Value:       "used",
+			Templates:   []string{"templates/Other.tpl.md", "templates/README.tpl.md"},
+		},
+		{
+			Placeholder: "unused",
+			Type:        tag.TypeDoc,
+			File:        "main.go",
+			Line:        4,
+			Column:      4,
+			EndLine:     5,
+			EndColumn:   9,
+			Value:       "unused",
+			Templates:   []string{},
+			Unused:      true,
+		},
+		{
+			Placeholder: "group_a",
+			Type:        tag.TypeDoc,
+			File:        "main.go",
+			Line:        7,
+			Column:      4,
+			EndLine:     8,
+			EndColumn:   4,
+			Value:       "a",
+			Templates:   []string{"templates/Other.tpl.md"},
+		},
+	}, got)
+}
@@ -0,0 +1,201 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+var ErrThemeNotFound = errors.New("the theme folder does not exist")
+
+// The layout files of a theme.
+const (
+	pageFile     = "page.gohtml"
+	notFoundFile = "404.gohtml"
+	sourceFile   = "source.gohtml"
+	tagsFile     = "tags.gohtml"
+
+	// themeStaticFolder is the folder of a theme which contains the static files.
+	themeStaticFolder = "static"
+)
+
+// @WHY readme_theme
+// The html pages of `atwhy serve` and `atwhy site` can be customized with a theme.
+// A theme is a folder in the project which is passed with `--theme path/to/theme`
+// (or `theme: path/to/theme` in the config file).
+// Everything in it replaces or extends the [built-in theme]({{ .Project "core/html" }}):
+// * `page.gohtml` is the layout of all pages.
+// * `404.gohtml` is shown for pages which do not exist (`404.html` in the static site).
+// * `source.gohtml` shows the project files linked by LINK tags in serve mode.
+//   `{{ .Escape "{{ .Source.Path }}" }}` is the path of the file and `{{ .Escape "{{ .Source.RawLink }}" }}` the link to the file itself.
+// * `tags.gohtml` is the tag explorer of the server. `{{ .Escape "{{ .Tags }}" }}` contains all tags,
+//   each with a `.Placeholder`, `.Type`, `.File`, `.Line`, `.Templates`, `.Unused` and `.Link` to the source viewer.
+// * All other `*.gohtml` files are partials which can be used with `{{ .Escape "{{ template \"name.gohtml\" . }}" }}`.
+//   The built-in theme uses `head.gohtml`, `nav.gohtml` and `scripts.gohtml`, so you can e.g. just replace the navigation.
+// * Files in the `static` folder are served at `{{ .Escape "{{ .StaticPath }}" }}` (e.g. `{{ .Escape "{{ .StaticPath }}" }}logo.png`).
+//   The built-in files are `atwhy.css`, `atwhy.js` and `highlight.css`.
+//
+// The layouts use the [Go html templates](https://pkg.go.dev/html/template) with the following data:
+// * `.ID`, `.Title`: the id and title of the current page.
+// * `.Body`: the generated html of the current page.
+// * `.Page`: the current page, e.g. `{{ .Escape "{{ .Page.Header.Meta.Title }}" }}`. (empty for the 404 page)
+// * `.Pages`: all pages.
+// * `.Nav`: the navigation tree. Each item has a `.Title`, `.Link`, `.Active`, `.Folder` and `.Children`.
+//   Folders only have a `.Link` if they contain an index page.
+// * `.Link`: creates the link to a page, e.g. `{{ .Escape "{{ range .Pages }}<a href=\"{{ $.Link . }}\">{{ .Header.Meta.Title }}</a>{{ end }}" }}`.
+// * `.BasePath`, `.StaticPath`: the paths of the site root and the static files.
+// * `.EventsURL`: only set if the page should reload automatically, see the `scripts.gohtml` of the built-in theme.
+
+// PageData is passed to the layouts of the theme.
+type PageData struct {
+	ID    string
+	Title string
+	Body  template.HTML
+
+	// Page is the current page. It is empty for the 404 page.
+	Page Page
+
+	Pages []Page
+
+	// Nav is the navigation tree built from the folders of the pages.
+	Nav []NavItem
+
+	// BasePath is the path of the site root (e.g. "/" or "../").
+	BasePath string
+
+	// StaticPath is the path of the css and js files of the page.
+	StaticPath string
+
+	// EventsURL is only set if the page should reload automatically on changes.
+	EventsURL string
+
+	// Source is only set for the source viewer.
+	Source *SourceData
+
+	// Tags is only set for the tag explorer.
+	Tags []TagInfo
+}
+
+// Link returns the link to the given page.
+func (d PageData) Link(page Page) string {
+	return d.BasePath + path.Join(filepath.ToSlash(page.Path), page.Name+".html")
+}
+
+// NavItem is an entry of the navigation tree.
+type NavItem struct {
+	// Title is the title of the page or the name of the folder.
+	Title string
+
+	// Link is the link to the page. Folders only have a link if they contain an index page.
+	Link string
+
+	// Active is true for the current page and all folders containing it.
+	Active bool
+
+	// Folder is true if the item is a folder.
+	Folder bool
+
+	Children []NavItem
+}
+
+// buildNav creates the navigation tree out of the folders of the pages.
+func (d PageData) buildNav() []NavItem {
+	var nav []NavItem
+	for _, page := range d.Pages {
+		item := NavItem{
+			Title:  page.Header.Meta.Title,
+			Link:   d.Link(page),
+			Active: page.ID != "" && page.ID == d.ID,
+		}
+
+		var folders []string
+		if page.Path != "." {
+			folders = strings.Split(filepath.ToSlash(page.Path), "/")
+		}
+		nav = insertNav(nav, folders, item, page.Header.Server.Index)
+	}
+	return nav
+}
+
+func insertNav(items []NavItem, folders []string, item NavItem, index bool) []NavItem {
+	if len(folders) == 0 {
+		return append(items, item)
+	}
+
+	pos := -1
+	for i := range items {
+		if items[i].Folder && items[i].Title == folders[0] {
+			pos = i
+			break
+		}
+	}
+	if pos == -1 {
+		items = append(items, NavItem{Title: folders[0], Folder: true})
+		pos = len(items) - 1
+	}
+
+	folder := &items[pos]
+	folder.Children = insertNav(folder.Children, folders[1:], item, index)
+	folder.Active = folder.Active || item.Active
+	if index && len(folders) == 1 {
+		folder.Link = item.Link
+	}
+
+	return items
+}
+
+// parseLayouts parses the built-in layouts and the ones of the theme.
+// It is done for each page, so that changes of the theme are used immediately.
+func (a *AtWhy) parseLayouts() (*template.Template, error) {
+	subFS, err := fs.Sub(TemplateFS, "html")
+	if err != nil {
+		return nil, err
+	}
+
+	layouts, err := template.ParseFS(subFS, "*.gohtml")
+	if err != nil {
+		return nil, err
+	}
+
+	if a.themeFS == nil {
+		return layouts, nil
+	}
+
+	themeFS := afero.NewIOFS(a.themeFS)
+	files, err := fs.Glob(themeFS, "*.gohtml")
+	if err != nil || len(files) == 0 {
+		return layouts, err
+	}
+
+	layouts, err = layouts.ParseFS(themeFS, files...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid theme: %w", err)
+	}
+	return layouts, nil
+}
+
+// themeStaticFile reads a static file of the theme.
+// It returns fs.ErrNotExist if there is no theme or the file does not exist in it.
+func (a *AtWhy) themeStaticFile(name string) ([]byte, fs.FileInfo, error) {
+	if a.themeFS == nil {
+		return nil, nil, fs.ErrNotExist
+	}
+
+	file := path.Join(themeStaticFolder, path.Clean("/"+name))
+	info, err := a.themeFS.Stat(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.IsDir() {
+		return nil, nil, fs.ErrNotExist
+	}
+
+	content, err := afero.ReadFile(a.themeFS, file)
+	return content, info, err
+}
@@ -0,0 +1,124 @@
+package core
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/generator"
+	mdTemplate "github.com/Tiffinger-Thiel-GmbH/atwhy/template"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPageData_buildNav(t *testing.T) {
+	page := func(id, path, title string, index bool) Page {
+		return Page{
+			ID:   id,
+			Name: title,
+			Path: path,
+			Header: mdTemplate.Header{
+				Meta:   mdTemplate.MetaData{Title: title},
+				Server: mdTemplate.ServerData{Index: index},
+			},
+		}
+	}
+
+	data := PageData{
+		ID:       "docu",
+		BasePath: "/",
+		Pages: []Page{
+			page("readme", ".", "README", true),
+			page("docu", "docu", "Docu", false),
+			page("docu-index", "docu", "Index", true),
+			page("deep", "docu/sub", "Deep", false),
+			page("other", "other", "Other", false),
+		},
+	}
+
+	assert.Equal(t, []NavItem{
+		{Title: "README", Link: "/README.html"},
+		{Title: "docu", Link: "/docu/Index.html", Active: true, Folder: true, Children: []NavItem{
+			{Title: "Docu", Link: "/docu/Docu.html", Active: true},
+			{Title: "Index", Link: "/docu/Index.html"},
+			{Title: "sub", Folder: true, Children: []NavItem{
+				{Title: "Deep", Link: "/docu/sub/Deep.html"},
+			}},
+		}},
+		{Title: "other", Folder: true, Children: []NavItem{
+			{Title: "Other", Link: "/other/Other.html"},
+		}},
+	}, data.buildNav())
+}
+
+func TestAtWhy_theme(t *testing.T) {
+	projectPath := t.TempDir()
+	files := map[string]string{
+		"templates/README.tpl.md": "# Readme\n",
+		"theme/page.gohtml":       `{{ template "nav.gohtml" . }}<main>{{ .Body }}</main>`,
+		"theme/nav.gohtml":        `{{ range .Nav }}<a href="{{ .Link }}">Branded {{ .Title }}</a>{{ end }}`,
+		"theme/static/logo.svg":   "<svg></svg>",
+		"theme/static/atwhy.css":  "body { color: red; }",
+	}
+	for name, content := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Join(projectPath, filepath.Dir(name)), 0775))
+		assert.NoError(t, os.WriteFile(filepath.Join(projectPath, name), []byte(content), 0664))
+	}
+
+	atwhy, err := New(&generator.HTML{}, projectPath, "/", "templates", nil, nil, Options{ThemeFolder: "theme"})
+	assert.NoError(t, err)
+
+	pages, err := atwhy.Load()
+	assert.NoError(t, err)
+
+	t.Run("layouts and partials of the theme are used", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		assert.NoError(t, atwhy.buildPage(buf, pages[0], pages, "/", false))
+		assert.Equal(t, `<a href="/README.html">Branded README</a><main><h1>Readme</h1>`+"\n</main>", buf.String())
+	})
+
+	t.Run("built-in layouts are still available", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		assert.NoError(t, atwhy.buildNotFound(buf, pages, "/", false))
+		assert.Contains(t, buf.String(), "Page not found")
+		assert.Contains(t, buf.String(), "Branded README")
+	})
+
+	t.Run("static files of the theme take precedence", func(t *testing.T) {
+		handler, err := atwhy.staticHandler()
+		assert.NoError(t, err)
+
+		for file, want := range map[string]string{
+			"logo.svg":  "<svg></svg>",
+			"atwhy.css": "body { color: red; }",
+		} {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/"+file, nil))
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, want, recorder.Body.String())
+		}
+
+		outputFS := afero.NewMemMapFs()
+		assert.NoError(t, atwhy.writeStaticFiles(outputFS))
+		content, err := afero.ReadFile(outputFS, staticPath+"atwhy.css")
+		assert.NoError(t, err)
+		assert.Equal(t, "body { color: red; }", string(content))
+		exists, err := afero.Exists(outputFS, staticPath+highlightFile)
+		assert.NoError(t, err)
+		assert.True(t, exists)
+	})
+
+	t.Run("missing theme", func(t *testing.T) {
+		_, err := New(&generator.HTML{}, projectPath, "/", "templates", nil, nil, Options{ThemeFolder: "nope"})
+		assert.ErrorIs(t, err, ErrThemeNotFound)
+	})
+
+	t.Run("invalid theme", func(t *testing.T) {
+		assert.NoError(t, os.WriteFile(filepath.Join(projectPath, "theme", "broken.gohtml"), []byte("{{ .Foo "), 0664))
+		_, err := New(&generator.HTML{}, projectPath, "/", "templates", nil, nil, Options{ThemeFolder: "theme"})
+		assert.Error(t, err)
+	})
+}
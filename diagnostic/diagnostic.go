@@ -0,0 +1,193 @@
+package diagnostic
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+
+	// SeverityOff is only used by Rules to drop the diagnostics of a rule.
+	SeverityOff Severity = "off"
+)
+
+var ErrUnknownSeverity = errors.New("unknown severity, possible values are: 'error', 'warning'")
+var ErrUnknownRuleSeverity = errors.New("unknown severity, possible values are: 'error', 'warning', 'off'")
+
+// ParseSeverity converts the given string into a Severity.
+func ParseSeverity(value string) (Severity, error) {
+	switch Severity(value) {
+	case SeverityError:
+		return SeverityError, nil
+	case SeverityWarning:
+		return SeverityWarning, nil
+	}
+
+	return "", ErrUnknownSeverity
+}
+
+// ParseRuleSeverity converts the given string into a Severity of a rule.
+// In addition to ParseSeverity it also accepts "off".
+func ParseRuleSeverity(value string) (Severity, error) {
+	if Severity(value) == SeverityOff {
+		return SeverityOff, nil
+	}
+
+	severity, err := ParseSeverity(value)
+	if err != nil {
+		return "", ErrUnknownRuleSeverity
+	}
+	return severity, nil
+}
+
+// Diagnostic describes a problem found in a file of the project.
+// It implements the error interface, so it can also be returned as error.
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+
+	// Code identifies the kind of the problem (e.g. "invalid-tag").
+	Code string `json:"code"`
+
+	// File is the path relative to the project root.
+	File string `json:"file"`
+
+	// Line is 1-based. 0 means that the line is unknown.
+	Line int `json:"line,omitempty"`
+
+	// Column is 1-based. 0 means that the column is unknown.
+	Column int `json:"column,omitempty"`
+
+	Message string `json:"message"`
+
+	// Err is the underlying error, if the Diagnostic was created from one.
+	Err error `json:"-"`
+}
+
+// String formats the Diagnostic like most compilers do:
+//
+//	file:line:column: severity: message
+//
+// Unknown lines and columns are left out.
+func (d Diagnostic) String() string {
+	location := d.File
+	if d.Line > 0 {
+		location += ":" + strconv.Itoa(d.Line)
+		if d.Column > 0 {
+			location += ":" + strconv.Itoa(d.Column)
+		}
+	}
+
+	return location + ": " + string(d.Severity) + ": " + d.Message
+}
+
+func (d Diagnostic) Error() string {
+	return d.String()
+}
+
+func (d Diagnostic) Unwrap() error {
+	return d.Err
+}
+
+// Reporter receives the diagnostics found while processing the project.
+type Reporter interface {
+	Report(d Diagnostic)
+}
+
+// Report is a helper which can be used to report to a possibly nil Reporter.
+func Report(reporter Reporter, d Diagnostic) {
+	if reporter != nil {
+		reporter.Report(d)
+	}
+}
+
+// Collector is a Reporter which just collects all diagnostics.
+// It is safe for concurrent use.
+// All methods can also be called on a nil Collector which then just does nothing.
+type Collector struct {
+	mutex       sync.Mutex
+	diagnostics []Diagnostic
+}
+
+func (c *Collector) Report(d Diagnostic) {
+	if c == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.diagnostics = append(c.diagnostics, d)
+}
+
+// Reset removes all collected diagnostics.
+func (c *Collector) Reset() {
+	if c == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.diagnostics = nil
+}
+
+// Diagnostics returns all collected diagnostics sorted by their location.
+func (c *Collector) Diagnostics() []Diagnostic {
+	if c == nil {
+		return nil
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	result := make([]Diagnostic, len(c.diagnostics))
+	copy(result, c.diagnostics)
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].File != result[j].File {
+			return result[i].File < result[j].File
+		}
+		if result[i].Line != result[j].Line {
+			return result[i].Line < result[j].Line
+		}
+		return result[i].Column < result[j].Column
+	})
+
+	return result
+}
+
+// Count returns the number of collected diagnostics with the given severity.
+func (c *Collector) Count(severity Severity) int {
+	count := 0
+	for _, d := range c.Diagnostics() {
+		if d.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
+
+// Rules maps the codes of diagnostics to the severity they should have.
+type Rules map[string]Severity
+
+// Apply returns the diagnostics with the severities of the Rules.
+// Diagnostics of rules which are off are removed, codes without a rule are kept as they are.
+func (r Rules) Apply(diagnostics []Diagnostic) []Diagnostic {
+	result := make([]Diagnostic, 0, len(diagnostics))
+	for _, d := range diagnostics {
+		severity, ok := r[d.Code]
+		if ok && severity == SeverityOff {
+			continue
+		}
+		if ok {
+			d.Severity = severity
+		}
+
+		result = append(result, d)
+	}
+	return result
+}
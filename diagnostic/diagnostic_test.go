@@ -0,0 +1,113 @@
+package diagnostic
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiagnostic_String(t *testing.T) {
+	tests := []struct {
+		name string
+		d    Diagnostic
+		want string
+	}{
+		{
+			name: "full location",
+			d:    Diagnostic{Severity: SeverityWarning, File: "main.go", Line: 3, Column: 5, Message: "a message"},
+			want: "main.go:3:5: warning: a message",
+		},
+		{
+			name: "without column",
+			d:    Diagnostic{Severity: SeverityError, File: "main.go", Line: 3, Message: "a message"},
+			want: "main.go:3: error: a message",
+		},
+		{
+			name: "without line the column is ignored",
+			d:    Diagnostic{Severity: SeverityError, File: "main.go", Column: 5, Message: "a message"},
+			want: "main.go: error: a message",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.d.String())
+			assert.Equal(t, tt.want, tt.d.Error())
+		})
+	}
+}
+
+func TestDiagnostic_Unwrap(t *testing.T) {
+	baseErr := errors.New("base")
+	var err error = Diagnostic{Err: baseErr}
+	assert.ErrorIs(t, err, baseErr)
+}
+
+func TestCollector(t *testing.T) {
+	c := &Collector{}
+	c.Report(Diagnostic{Severity: SeverityWarning, File: "b.go", Line: 1})
+	c.Report(Diagnostic{Severity: SeverityError, File: "a.go", Line: 10, Column: 2})
+	c.Report(Diagnostic{Severity: SeverityWarning, File: "a.go", Line: 10, Column: 1})
+	c.Report(Diagnostic{Severity: SeverityWarning, File: "a.go", Line: 2})
+
+	assert.Equal(t, []Diagnostic{
+		{Severity: SeverityWarning, File: "a.go", Line: 2},
+		{Severity: SeverityWarning, File: "a.go", Line: 10, Column: 1},
+		{Severity: SeverityError, File: "a.go", Line: 10, Column: 2},
+		{Severity: SeverityWarning, File: "b.go", Line: 1},
+	}, c.Diagnostics())
+	assert.Equal(t, 3, c.Count(SeverityWarning))
+	assert.Equal(t, 1, c.Count(SeverityError))
+
+	c.Reset()
+	assert.Empty(t, c.Diagnostics())
+
+	t.Run("nil collector", func(t *testing.T) {
+		var c *Collector
+		c.Report(Diagnostic{})
+		c.Reset()
+		assert.Nil(t, c.Diagnostics())
+		assert.Equal(t, 0, c.Count(SeverityWarning))
+	})
+}
+
+func TestParseRuleSeverity(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Severity
+		wantErr error
+	}{
+		{value: "error", want: SeverityError},
+		{value: "warning", want: SeverityWarning},
+		{value: "off", want: SeverityOff},
+		{value: "info", wantErr: ErrUnknownRuleSeverity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseRuleSeverity(tt.value)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRules_Apply(t *testing.T) {
+	rules := Rules{
+		"unused-tag":  SeverityOff,
+		"missing-tag": SeverityWarning,
+		"empty-tag":   SeverityError,
+	}
+
+	got := rules.Apply([]Diagnostic{
+		{Severity: SeverityWarning, Code: "unused-tag", File: "a.go"},
+		{Severity: SeverityError, Code: "missing-tag", File: "b.go"},
+		{Severity: SeverityWarning, Code: "empty-tag", File: "c.go"},
+		{Severity: SeverityWarning, Code: "invalid-tag", File: "d.go"},
+	})
+
+	assert.Equal(t, []Diagnostic{
+		{Severity: SeverityWarning, Code: "missing-tag", File: "b.go"},
+		{Severity: SeverityError, Code: "empty-tag", File: "c.go"},
+		{Severity: SeverityWarning, Code: "invalid-tag", File: "d.go"},
+	}, got)
+}
@@ -2,13 +2,13 @@ package finder
 
 import (
 	"bufio"
-	"fmt"
 	"io"
 	"path/filepath"
 	"regexp"
 	"strings"
 
 	"github.com/Tiffinger-Thiel-GmbH/atwhy/core/tag"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/diagnostic"
 )
 
 type CommentConfig struct {
@@ -21,10 +21,25 @@ type CommentConfig struct {
 // It takes into account block comments (e.g. /* .... */) and line comments
 // (e.g. // ...). You can pass alternative comment indicators to
 // support other languages.
+//
+// The Finder keeps no state between calls of Find, so it can be used
+// from several goroutines at the same time as long as the Reporter is
+// safe for concurrent use.
 type Finder struct {
 	// CommentConfig maps the filetype (e.g. ".go") to the matching CommentConfig.
 	CommentConfig map[string]CommentConfig
 
+	// Reporter receives warnings about invalid tags.
+	// It may be nil.
+	Reporter diagnostic.Reporter
+}
+
+// scanState contains the parsing state of a single Find call.
+type scanState struct {
+	cfg      CommentConfig
+	reporter diagnostic.Reporter
+	filename string
+
 	currentlyInBlockComment  bool
 	currentLineIsLineComment bool
 
@@ -41,33 +56,62 @@ type Finder struct {
 	// includeCode saves if a \@WHY CODE tag was found.
 	// It has to be reset at a \@WHY CODE_END tag.
 	includeCode bool
+
+	// codeStart is reported if the last \@WHY CODE tag is not closed.
+	codeStart diagnostic.Diagnostic
 }
 
-func (f *Finder) finishTag(res []tag.Raw) []tag.Raw {
-	if f.currentTag != nil {
+// extendTag sets the end of the current tag to the end of the content in the line.
+// Blank lines do not change the end, as they are trimmed from the tag anyway.
+func (s *scanState) extendTag(lineNum int, line string, content string) {
+	content = strings.TrimRight(content, " \t\r\n")
+	if strings.TrimSpace(content) == "" {
+		return
+	}
+
+	s.currentTag.EndLine = lineNum + 1
+	s.currentTag.EndColumn = strings.Index(line, content) + len(content)
+}
 
-		f.currentTag.Value = f.currentTag.Value + f.currentCommentLine
+func (s *scanState) finishTag(res []tag.Raw) []tag.Raw {
+	if s.currentTag != nil {
+
+		s.currentTag.Value = s.currentTag.Value + s.currentCommentLine
 
 		// Unescape \@ to @
-		f.currentTag.Value = strings.ReplaceAll(f.currentTag.Value, "\\@", "@")
-		res = append(res, *f.currentTag)
-		f.currentTag = nil
+		s.currentTag.Value = strings.ReplaceAll(s.currentTag.Value, "\\@", "@")
+		res = append(res, *s.currentTag)
+		s.currentTag = nil
 	}
 
 	return res
 }
 
-func (f *Finder) reset() {
-	f.currentlyInBlockComment = false
-	f.currentLineIsLineComment = false
-	f.currentCommentLine = ""
-	f.currentBlockIndex = -1
-	f.currentTag = nil
-	f.includeCode = false
+func (f *Finder) Find(filename string, reader io.Reader) ([]tag.Raw, error) {
+	return f.FindAndReport(filename, reader, f.Reporter)
 }
 
-func (f *Finder) Find(filename string, reader io.Reader) ([]tag.Raw, error) {
-	f.reset()
+// FindAndReport works like Find but reports the diagnostics to the given reporter instead of the Reporter of the Finder.
+func (f *Finder) FindAndReport(filename string, reader io.Reader, reporter diagnostic.Reporter) ([]tag.Raw, error) {
+	commentCFG, found := f.CommentConfig[filepath.Ext(filename)]
+	if !found {
+		commentCFG, found = f.CommentConfig["."]
+		if !found {
+			return nil, nil
+		}
+	}
+
+	s := &scanState{
+		cfg:               commentCFG,
+		reporter:          reporter,
+		filename:          filename,
+		currentBlockIndex: -1,
+	}
+	return s.find(reader)
+}
+
+func (s *scanState) find(reader io.Reader) ([]tag.Raw, error) {
+	filename := s.filename
 
 	var res []tag.Raw
 	var scan = bufio.NewScanner(reader)
@@ -77,50 +121,63 @@ func (f *Finder) Find(filename string, reader io.Reader) ([]tag.Raw, error) {
 		lineNum++
 
 		line := scan.Text()
-		commentCFG, found := f.CommentConfig[filepath.Ext(filename)]
-		if !found {
-			commentCFG, found = f.CommentConfig["."]
-			if !found {
-				return res, nil
-			}
-		}
-		f.findComment(commentCFG, line)
+		s.findComment(line)
 
 		// Finish the current tag if there is no more comment line (or includeCode).
-		if !f.currentlyInBlockComment &&
-			!f.currentLineIsLineComment &&
-			!f.includeCode {
-			res = f.finishTag(res)
+		if !s.currentlyInBlockComment &&
+			!s.currentLineIsLineComment &&
+			!s.includeCode {
+			res = s.finishTag(res)
 			continue
 		}
 
-		if f.currentCommentLine != "" {
-			newTag := f.findTag()
+		if s.currentCommentLine != "" {
+			newTag := s.findTag(filename, lineNum, line)
 			if newTag != nil {
 				// Special tag CODE
 				if newTag.Type == tag.TypeCode {
-					f.includeCode = true
+					s.includeCode = true
+					s.codeStart = diagnostic.Diagnostic{
+						Severity: diagnostic.SeverityWarning,
+						Code:     "unclosed-code",
+						File:     filename,
+						Line:     lineNum + 1,
+						Column:   newTag.Column,
+						Message:  "the @WHY CODE " + newTag.Placeholder + " is not closed by a @WHY CODE_END, it includes the rest of the file",
+					}
 				}
 
 				// Special tag CODE_END
 				if newTag.Type == tag.TypeCodeEnd {
-					f.includeCode = false
-					f.currentCommentLine = ""
-					res = f.finishTag(res)
+					if !s.includeCode {
+						diagnostic.Report(s.reporter, diagnostic.Diagnostic{
+							Severity: diagnostic.SeverityWarning,
+							Code:     "unexpected-code-end",
+							File:     filename,
+							Line:     lineNum + 1,
+							Column:   newTag.Column,
+							Message:  "found a @WHY CODE_END without a matching @WHY CODE",
+						})
+					}
+
+					s.includeCode = false
+					s.currentCommentLine = ""
+					res = s.finishTag(res)
 					continue
 				}
 
 				// Finish the previous tag and start a new one.
-				f.currentCommentLine = ""
-				res = f.finishTag(res)
+				s.currentCommentLine = ""
+				res = s.finishTag(res)
 				newTag.Filename = filename
-				newTag.Line = lineNum
-				f.currentTag = newTag
+				newTag.Line = lineNum + 1
+				s.currentTag = newTag
+				s.extendTag(lineNum, line, newTag.Value)
 
 				// Special tag LINK doesn't need any additional lines,
 				// we can stop here.
 				if newTag.Type == tag.TypeLink {
-					res = f.finishTag(res)
+					res = s.finishTag(res)
 					continue
 				}
 
@@ -128,55 +185,62 @@ func (f *Finder) Find(filename string, reader io.Reader) ([]tag.Raw, error) {
 			}
 
 			// If includeCode == false just add the current line to the value.
-			if f.currentTag != nil && !f.includeCode {
-				f.currentTag.Value = f.currentTag.Value + f.currentCommentLine + "\n"
+			if s.currentTag != nil && !s.includeCode {
+				s.currentTag.Value = s.currentTag.Value + s.currentCommentLine + "\n"
+				s.extendTag(lineNum, line, s.currentCommentLine)
 				continue
 			}
 		}
 
 		// If includeCode == true, add the whole line without trimming.
-		if f.currentTag != nil && f.includeCode {
-			f.currentTag.Value = f.currentTag.Value + line + "\n"
+		if s.currentTag != nil && s.includeCode {
+			s.currentTag.Value = s.currentTag.Value + line + "\n"
+			s.extendTag(lineNum, line, line)
 			continue
 		}
 
 		// For empty comment lines, just add newlines.
-		if f.currentTag != nil && f.currentCommentLine == "" && (f.currentlyInBlockComment || f.currentLineIsLineComment) {
-			f.currentTag.Value = f.currentTag.Value + "\n"
+		if s.currentTag != nil && s.currentCommentLine == "" && (s.currentlyInBlockComment || s.currentLineIsLineComment) {
+			s.currentTag.Value = s.currentTag.Value + "\n"
 			continue
 		}
 
-		f.currentCommentLine = ""
+		s.currentCommentLine = ""
+	}
+
+	if s.includeCode {
+		diagnostic.Report(s.reporter, s.codeStart)
 	}
 
 	// Finish the last tag.
-	f.currentCommentLine = ""
-	res = f.finishTag(res)
+	s.currentCommentLine = ""
+	res = s.finishTag(res)
 
 	return res, nil
 }
 
-// findComment and sets the struct-variables
+// findComment and sets the variables
 // currentCommentLine, currentLineIsLineComment, currentBlockIndex, currentlyInBlockComment
 // accordingly.
-func (f *Finder) findComment(cfg CommentConfig, line string) {
+func (s *scanState) findComment(line string) {
+	cfg := s.cfg
 	defer func() {
 		// Always cut the first space because usually comments have a space after the comment sign.
-		f.currentCommentLine = strings.TrimPrefix(f.currentCommentLine, " ")
+		s.currentCommentLine = strings.TrimPrefix(s.currentCommentLine, " ")
 	}()
 
-	f.currentCommentLine = ""
-	f.currentLineIsLineComment = false
+	s.currentCommentLine = ""
+	s.currentLineIsLineComment = false
 
 	var commentStartedInThisLine bool
 	trimmedLine := strings.TrimLeft(line, " \t")
-	if !f.currentlyInBlockComment {
+	if !s.currentlyInBlockComment {
 
 		// First check if it is a One-Line comment. (e.g. //)
 		for _, lineCommentStart := range cfg.LineComment {
-			if !f.currentlyInBlockComment && strings.HasPrefix(trimmedLine, lineCommentStart) {
-				f.currentLineIsLineComment = true
-				f.currentCommentLine = strings.TrimLeft(trimmedLine, lineCommentStart)
+			if !s.currentlyInBlockComment && strings.HasPrefix(trimmedLine, lineCommentStart) {
+				s.currentLineIsLineComment = true
+				s.currentCommentLine = strings.TrimLeft(trimmedLine, lineCommentStart)
 				return
 			}
 		}
@@ -184,9 +248,9 @@ func (f *Finder) findComment(cfg CommentConfig, line string) {
 		// Then check if it is in a block comment.
 		for blockIndex, blockCommentStart := range cfg.BlockStart {
 			if strings.HasPrefix(trimmedLine, blockCommentStart) {
-				f.currentBlockIndex = blockIndex
-				f.currentlyInBlockComment = true
-				f.currentCommentLine = strings.TrimLeft(trimmedLine, blockCommentStart)
+				s.currentBlockIndex = blockIndex
+				s.currentlyInBlockComment = true
+				s.currentCommentLine = strings.TrimLeft(trimmedLine, blockCommentStart)
 				commentStartedInThisLine = true
 				break
 			}
@@ -194,24 +258,24 @@ func (f *Finder) findComment(cfg CommentConfig, line string) {
 	}
 
 	// Try to find the end of the block comment.
-	if f.currentlyInBlockComment {
+	if s.currentlyInBlockComment {
 		linePart := trimmedLine
 		if commentStartedInThisLine {
-			linePart = f.currentCommentLine
+			linePart = s.currentCommentLine
 		}
 
 		for _, blockCommentEnd := range cfg.BlockEnd {
 
 			if foundIndex := strings.Index(linePart, blockCommentEnd); foundIndex > -1 {
-				f.currentBlockIndex = -1
-				f.currentlyInBlockComment = false
-				f.currentCommentLine = linePart[:foundIndex]
+				s.currentBlockIndex = -1
+				s.currentlyInBlockComment = false
+				s.currentCommentLine = linePart[:foundIndex]
 				return
 			}
 		}
 
-		f.currentBlockIndex = -1
-		f.currentCommentLine = linePart
+		s.currentBlockIndex = -1
+		s.currentCommentLine = linePart
 	}
 }
 
@@ -222,6 +286,7 @@ func (f *Finder) findComment(cfg CommentConfig, line string) {
 //	DOC CODE any_name
 //	DOC CODE_END
 //	DOC LINK any_name
+//	DOC +any_name
 //
 // @WHY readme_tags2_rules
 // The placeholder_names must follow these rules:
@@ -234,30 +299,45 @@ func (f *Finder) findComment(cfg CommentConfig, line string) {
 // Examles:
 //   - any_tag_name
 //   - supertag
-var anyTagRegex = regexp.MustCompile(`([\\]?)@WHY( ([A-Z_]+))?( ([a-z]+[a-z_0-9]*))? *$`)
+var anyTagRegex = regexp.MustCompile(`([\\]?)@WHY( ([A-Z_]+))?( (\+?)([a-z]+[a-z_0-9]*))? *$`)
 
 var anyAtWhyRegex = regexp.MustCompile(`([\\]?)@WHY`)
 
+// column returns the 1-based column of the first \@WHY in the line which is not escaped.
+func column(line string) int {
+	for _, match := range anyAtWhyRegex.FindAllStringSubmatchIndex(line, -1) {
+		if match[2] == match[3] {
+			return match[0] + 1
+		}
+	}
+	return strings.Index(line, "@WHY") + 1
+}
+
 // findTag using the anyTagRegex.
 // It already pre-fills the first comment line if a new one was found.
-func (f *Finder) findTag() *tag.Raw {
-	isAtWhy := anyAtWhyRegex.MatchString(f.currentCommentLine)
+// The filename, lineNum and line are only used to report invalid tags.
+func (s *scanState) findTag(filename string, lineNum int, line string) *tag.Raw {
+	isAtWhy := anyAtWhyRegex.MatchString(s.currentCommentLine)
 	if !isAtWhy {
 		return nil
 	}
 
-	matches := anyTagRegex.FindAllStringSubmatch(f.currentCommentLine, 1)
-	if matches == nil {
-		if !strings.Contains(f.currentCommentLine, "\\@WHY") {
-			// Found \@WHY but it is not valid. Print a info for the user.
-			fmt.Printf("found a @WHY which doesn't match the required format:\n%v\n", f.currentCommentLine)
+	match := anyTagRegex.FindStringSubmatch(s.currentCommentLine)
+	if match == nil {
+		if !strings.Contains(s.currentCommentLine, "\\@WHY") {
+			// Found \@WHY but it is not valid. Report it to the user.
+			diagnostic.Report(s.reporter, diagnostic.Diagnostic{
+				Severity: diagnostic.SeverityWarning,
+				Code:     "invalid-tag",
+				File:     filename,
+				Line:     lineNum + 1,
+				Column:   column(line),
+				Message:  "found a @WHY which doesn't match the required format: " + strings.TrimSpace(s.currentCommentLine),
+			})
 		}
 		return nil
 	}
 
-	// Per line, we only need one match.
-	match := matches[0]
-
 	// Ignore escaped \@WHY
 	if match[1] != "" {
 		return nil
@@ -265,8 +345,16 @@ func (f *Finder) findTag() *tag.Raw {
 
 	newTag := tag.Raw{
 		Type:        tag.Type(match[3]),
-		Placeholder: match[5],
-		Value:       f.currentCommentLine + "\n",
+		Placeholder: match[6],
+		Value:       s.currentCommentLine + "\n",
+		Accumulate:  match[5] == "+",
+
+		// The match is at the end of the comment, so its last occurrence in the line is the tag
+		// (and not e.g. an escaped \@WHY before it).
+		Column: strings.LastIndex(line, match[0]) + 1,
+	}
+	if newTag.Column == 0 {
+		newTag.Column = column(line)
 	}
 
 	// If none was given, it is a DOC.
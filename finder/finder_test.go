@@ -3,9 +3,11 @@ package finder
 import (
 	"io"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/Tiffinger-Thiel-GmbH/atwhy/core/tag"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/diagnostic"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -31,6 +33,8 @@ func TestFinder_Find(t *testing.T) {
 		args    args
 		want    []tag.Raw
 		wantErr assert.ErrorAssertionFunc
+
+		wantDiagnostics []diagnostic.Diagnostic
 	}{
 		{
 			name: "normal @WHY tags",
@@ -63,7 +67,10 @@ No part of comment`),
 					Type:        tag.TypeDoc,
 					Placeholder: "my_tag_name",
 					Filename:    "file.go",
-					Line:        1,
+					Line:        2,
+					Column:      4,
+					EndLine:     5,
+					EndColumn:   21,
 					Value: `@WHY my_tag_name
 Some text
 
@@ -74,7 +81,10 @@ Another text block
 					Type:        tag.TypeDoc,
 					Placeholder: "my_other_tag_name",
 					Filename:    "file.go",
-					Line:        10,
+					Line:        11,
+					Column:      1,
+					EndLine:     16,
+					EndColumn:   9,
 					Value: `@WHY my_other_tag_name
 A Block comment.
 ...
@@ -143,7 +153,10 @@ NOT included
 					Type:        tag.TypeCode,
 					Placeholder: "my_tag_name",
 					Filename:    "file.go",
-					Line:        1,
+					Line:        2,
+					Column:      4,
+					EndLine:     11,
+					EndColumn:   6,
 					Value: `@WHY CODE my_tag_name
 // Some text
 //
@@ -178,7 +191,10 @@ asd
 					Type:        tag.TypeLink,
 					Placeholder: "my_link_tag",
 					Filename:    "file.go",
-					Line:        1,
+					Line:        2,
+					Column:      4,
+					EndLine:     2,
+					EndColumn:   24,
 					Value: `@WHY LINK my_link_tag
 `,
 				},
@@ -186,7 +202,10 @@ asd
 					Type:        tag.TypeLink,
 					Placeholder: "another_link_tag",
 					Filename:    "file.go",
-					Line:        4,
+					Line:        5,
+					Column:      4,
+					EndLine:     5,
+					EndColumn:   29,
 					Value: `@WHY LINK another_link_tag
 `,
 				},
@@ -224,12 +243,155 @@ asd
 			},
 			want:    nil,
 			wantErr: assert.NoError,
+			wantDiagnostics: []diagnostic.Diagnostic{
+				{
+					Severity: diagnostic.SeverityWarning,
+					Code:     "invalid-tag",
+					File:     "file.go",
+					Line:     2,
+					Column:   4,
+					Message:  "found a @WHY which doesn't match the required format: @WHY LINK my-tag",
+				},
+			},
+		},
+		{
+			name: "unclosed CODE",
+			fields: fields{
+				CommentConfig: testCommentConfig,
+			},
+			args: args{
+				filename: "file.go",
+				reader: strings.NewReader(`This is some fil
+	// @WHY CODE my_code
+	func main() {}
+`),
+			},
+			want: []tag.Raw{
+				{
+					Type:        tag.TypeCode,
+					Placeholder: "my_code",
+					Filename:    "file.go",
+					Line:        2,
+					Column:      5,
+					EndLine:     3,
+					EndColumn:   15,
+					Value: `@WHY CODE my_code
+	func main() {}
+`,
+				},
+			},
+			wantErr: assert.NoError,
+			wantDiagnostics: []diagnostic.Diagnostic{
+				{
+					Severity: diagnostic.SeverityWarning,
+					Code:     "unclosed-code",
+					File:     "file.go",
+					Line:     2,
+					Column:   5,
+					Message:  "the @WHY CODE my_code is not closed by a @WHY CODE_END, it includes the rest of the file",
+				},
+			},
+		},
+		{
+			name: "CODE_END without CODE",
+			fields: fields{
+				CommentConfig: testCommentConfig,
+			},
+			args: args{
+				filename: "file.go",
+				reader: strings.NewReader(`func main() {}
+// @WHY CODE_END
+`),
+			},
+			want:    nil,
+			wantErr: assert.NoError,
+			wantDiagnostics: []diagnostic.Diagnostic{
+				{
+					Severity: diagnostic.SeverityWarning,
+					Code:     "unexpected-code-end",
+					File:     "file.go",
+					Line:     2,
+					Column:   4,
+					Message:  "found a @WHY CODE_END without a matching @WHY CODE",
+				},
+			},
+		},
+		{
+			name: "escaped @WHY before the tag",
+			fields: fields{
+				CommentConfig: testCommentConfig,
+			},
+			args: args{
+				filename: "file.go",
+				reader: strings.NewReader(`// use \@WHY like this: @WHY my_tag
+// text
+`),
+			},
+			want: []tag.Raw{
+				{
+					Type:        tag.TypeDoc,
+					Placeholder: "my_tag",
+					Filename:    "file.go",
+					Line:        1,
+					Column:      25,
+					EndLine:     2,
+					EndColumn:   7,
+					Value: `use @WHY like this: @WHY my_tag
+text
+`,
+				},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "accumulating tags",
+			fields: fields{
+				CommentConfig: testCommentConfig,
+			},
+			args: args{
+				filename: "file.go",
+				reader: strings.NewReader(`// @WHY +my_list
+// first
+func main() {}
+// @WHY LINK +my_list
+`),
+			},
+			want: []tag.Raw{
+				{
+					Type:        tag.TypeDoc,
+					Placeholder: "my_list",
+					Accumulate:  true,
+					Filename:    "file.go",
+					Line:        1,
+					Column:      4,
+					EndLine:     2,
+					EndColumn:   8,
+					Value: `@WHY +my_list
+first
+`,
+				},
+				{
+					Type:        tag.TypeLink,
+					Placeholder: "my_list",
+					Accumulate:  true,
+					Filename:    "file.go",
+					Line:        4,
+					Column:      4,
+					EndLine:     4,
+					EndColumn:   21,
+					Value: `@WHY LINK +my_list
+`,
+				},
+			},
+			wantErr: assert.NoError,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			diagnostics := &diagnostic.Collector{}
 			f := &Finder{
 				CommentConfig: tt.fields.CommentConfig,
+				Reporter:      diagnostics,
 			}
 			got, err := f.Find(tt.args.filename, tt.args.reader)
 			if !tt.wantErr(t, err) {
@@ -237,6 +399,42 @@ asd
 			}
 
 			assert.EqualValues(t, tt.want, got)
+			assert.ElementsMatch(t, tt.wantDiagnostics, diagnostics.Diagnostics())
 		})
 	}
 }
+
+func TestFinder_Find_concurrent(t *testing.T) {
+	f := &Finder{
+		CommentConfig: testCommentConfig,
+		Reporter:      &diagnostic.Collector{},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			// Every second file stops inside of a block comment to check that no state leaks into other calls.
+			content := "// @WHY tag\n// value\npackage main\n"
+			if i%2 == 0 {
+				content += "/* @WHY open\n"
+			}
+
+			got, err := f.Find("main.go", strings.NewReader(content))
+			assert.NoError(t, err)
+			assert.Equal(t, tag.Raw{
+				Type:        tag.TypeDoc,
+				Placeholder: "tag",
+				Filename:    "main.go",
+				Value:       "@WHY tag\nvalue\n",
+				Line:        1,
+				Column:      4,
+				EndLine:     2,
+				EndColumn:   8,
+			}, got[0])
+		}(i)
+	}
+	wg.Wait()
+}
@@ -5,12 +5,23 @@ import (
 	"strings"
 
 	mdTemplate "github.com/Tiffinger-Thiel-GmbH/atwhy/template"
+	"github.com/alecthomas/chroma"
+	chromahtml "github.com/alecthomas/chroma/formatters/html"
+	"github.com/alecthomas/chroma/lexers"
+	"github.com/alecthomas/chroma/styles"
 	"github.com/yuin/goldmark"
 	highlighting "github.com/yuin/goldmark-highlighting"
 )
 
+// HighlightStyle is the chroma style used for the syntax highlighting.
+const HighlightStyle = "monokai"
+
 type HTML struct {
 	Markdown
+
+	// Classes uses css classes instead of inline styles for the syntax highlighting.
+	// The matching css can be generated with WriteHighlightCSS.
+	Classes bool
 }
 
 func (h HTML) Ext() string {
@@ -21,7 +32,10 @@ func (h HTML) Generate(markdownTemplate mdTemplate.Markdown, writer io.Writer) e
 	gm := goldmark.New(
 		goldmark.WithExtensions(
 			highlighting.NewHighlighting(
-				highlighting.WithStyle("monokai"),
+				highlighting.WithStyle(HighlightStyle),
+				highlighting.WithFormatOptions(
+					chromahtml.WithClasses(h.Classes),
+				),
 			),
 		),
 	)
@@ -35,3 +49,38 @@ func (h HTML) Generate(markdownTemplate mdTemplate.Markdown, writer io.Writer) e
 
 	return gm.Convert([]byte(resMD.String()), writer)
 }
+
+// WriteHighlightCSS writes the css needed for the syntax highlighting if HTML.Classes is used.
+func WriteHighlightCSS(writer io.Writer) error {
+	return chromahtml.New(chromahtml.WithClasses(true)).WriteCSS(writer, styles.Get(HighlightStyle))
+}
+
+// LineAnchorPrefix is the prefix of the ids of the lines written by HighlightSource, e.g. "#L42".
+const LineAnchorPrefix = "L"
+
+// HighlightSource writes the content of a source file as html with syntax highlighting and linkable line numbers.
+// The language is detected by the filename, or by the content if that is not possible.
+// The given ranges of lines (first and last line, starting at 1) are highlighted.
+// It uses css classes, see WriteHighlightCSS.
+func HighlightSource(writer io.Writer, filename string, content string, highlight [][2]int) error {
+	lexer := lexers.Match(filename)
+	if lexer == nil {
+		lexer = lexers.Analyse(content)
+	}
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, content)
+	if err != nil {
+		return err
+	}
+
+	formatter := chromahtml.New(
+		chromahtml.WithClasses(true),
+		chromahtml.WithLineNumbers(true),
+		chromahtml.LinkableLineNumbers(true, LineAnchorPrefix),
+		chromahtml.HighlightLines(highlight),
+	)
+	return formatter.Format(writer, styles.Get(HighlightStyle), iterator)
+}
@@ -1,6 +1,7 @@
 package generator
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -18,3 +19,21 @@ func TestHTML_Generate(t *testing.T) {
 	// And also we do not want to test Goldmark.
 	t.Skip()
 }
+
+func TestWriteHighlightCSS(t *testing.T) {
+	writer := &bytes.Buffer{}
+	assert.NoError(t, WriteHighlightCSS(writer))
+	assert.Contains(t, writer.String(), ".chroma {")
+}
+
+func TestHighlightSource(t *testing.T) {
+	writer := &bytes.Buffer{}
+	assert.NoError(t, HighlightSource(writer, "main.go", "package main\n\nfunc main() {\n}\n", [][2]int{{3, 4}}))
+
+	got := writer.String()
+	assert.Contains(t, got, `id="L1"`)
+	assert.Contains(t, got, `href="#L4"`)
+	assert.Contains(t, got, `<span class="kn">package</span>`)
+	assert.Contains(t, got, `<span class="line hl"><span class="ln" id="L3">`)
+	assert.NotContains(t, got, `<span class="line hl"><span class="ln" id="L2">`)
+}
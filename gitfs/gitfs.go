@@ -0,0 +1,415 @@
+// Package gitfs provides read-only access to the files of a git revision
+// directly from the object store of a local repository.
+package gitfs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/spf13/afero"
+)
+
+var ErrNotRepository = errors.New("the project is not inside of a git repository")
+
+var ErrRevisionNotFound = errors.New("the git revision does not exist")
+
+// FS implements fs.FS for the tree of a git commit.
+// All files have the time of the commit as modification time.
+type FS struct {
+	tree    *object.Tree
+	modTime time.Time
+}
+
+var (
+	_ fs.StatFS    = FS{}
+	_ fs.ReadDirFS = FS{}
+)
+
+// New opens the git repository containing the projectPath and returns the files of the
+// given revision (e.g. a commit hash, tag or branch) as read-only afero.Fs.
+// The root of the returned FS is the projectPath, even if it is a sub folder of the repository.
+func New(projectPath string, revision string) (afero.Fs, error) {
+	gitFS, err := Open(projectPath, revision)
+	if err != nil {
+		return nil, err
+	}
+	return aferoFS{FromIOFS: afero.FromIOFS{FS: gitFS}}, nil
+}
+
+// aferoFS also accepts the paths used by afero (e.g. "/dir/file" or "dir\file")
+// which are not valid for fs.FS.
+type aferoFS struct {
+	afero.FromIOFS
+}
+
+func (f aferoFS) Open(name string) (afero.File, error) {
+	return f.FromIOFS.Open(clean(name))
+}
+
+func (f aferoFS) OpenFile(name string, flag int, perm fs.FileMode) (afero.File, error) {
+	return f.FromIOFS.OpenFile(clean(name), flag, perm)
+}
+
+func (f aferoFS) Stat(name string) (fs.FileInfo, error) {
+	return f.FromIOFS.Stat(clean(name))
+}
+
+// Open works the same as New but returns the FS itself.
+func Open(projectPath string, revision string) (FS, error) {
+	repo, err := openRepository(projectPath)
+	if err != nil {
+		return FS{}, err
+	}
+
+	commit, err := resolveCommit(repo, revision)
+	if err != nil {
+		return FS{}, err
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return FS{}, err
+	}
+
+	projectFolder, err := projectFolder(repo, projectPath)
+	if err != nil {
+		return FS{}, err
+	}
+	if projectFolder != "." {
+		tree, err = tree.Tree(projectFolder)
+		if err != nil {
+			return FS{}, fmt.Errorf("the project folder %v does not exist in %v: %w", projectFolder, revision, err)
+		}
+	}
+
+	return FS{
+		tree:    tree,
+		modTime: commit.Committer.When,
+	}, nil
+}
+
+// Revision describes a resolved git revision of the project.
+type Revision struct {
+	// Commit is the full hash of the commit.
+	Commit string
+
+	// Repo is the path of the "origin" remote without host and ".git" suffix (e.g. "owner/name").
+	// It is empty if there is no such remote.
+	Repo string
+
+	// Folder is the path of the project relative to the repository root, "." if it is the root itself.
+	Folder string
+}
+
+// Resolve opens the git repository containing the projectPath and resolves the given revision.
+func Resolve(projectPath string, revision string) (Revision, error) {
+	repo, err := openRepository(projectPath)
+	if err != nil {
+		return Revision{}, err
+	}
+
+	commit, err := resolveCommit(repo, revision)
+	if err != nil {
+		return Revision{}, err
+	}
+
+	folder, err := projectFolder(repo, projectPath)
+	if err != nil {
+		return Revision{}, err
+	}
+
+	result := Revision{
+		Commit: commit.Hash.String(),
+		Folder: folder,
+	}
+
+	remote, err := repo.Remote(git.DefaultRemoteName)
+	if err != nil && !errors.Is(err, git.ErrRemoteNotFound) {
+		return Revision{}, err
+	}
+	if remote != nil && len(remote.Config().URLs) > 0 {
+		result.Repo = repoName(remote.Config().URLs[0])
+	}
+
+	return result, nil
+}
+
+func openRepository(projectPath string) (*git.Repository, error) {
+	repo, err := git.PlainOpenWithOptions(projectPath, &git.PlainOpenOptions{DetectDotGit: true})
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		return nil, fmt.Errorf("%w: %v", ErrNotRepository, projectPath)
+	}
+	return repo, err
+}
+
+func resolveCommit(repo *git.Repository, revision string) (*object.Commit, error) {
+	hash, err := repo.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v (%v)", ErrRevisionNotFound, revision, err)
+	}
+
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, fmt.Errorf("%v is no commit: %w", revision, err)
+	}
+	return commit, nil
+}
+
+// projectFolder returns the path of the project relative to the repository root.
+// Bare repositories have no worktree, so the project is always the root.
+func projectFolder(repo *git.Repository, projectPath string) (string, error) {
+	worktree, err := repo.Worktree()
+	if errors.Is(err, git.ErrIsBareRepository) {
+		return ".", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return relativeFolder(worktree.Filesystem.Root(), projectPath)
+}
+
+// repoName extracts the path of the repository from a remote url
+// like "https://github.com/owner/name.git" or "git@github.com:owner/name.git".
+func repoName(remoteURL string) string {
+	name := remoteURL
+	if i := strings.Index(name, "://"); i >= 0 {
+		// Remove the scheme and the host.
+		name = name[i+len("://"):]
+		if i := strings.Index(name, "/"); i >= 0 {
+			name = name[i+1:]
+		} else {
+			name = ""
+		}
+	} else if i := strings.Index(name, ":"); i >= 0 {
+		// scp-like syntax: user@host:path
+		name = name[i+1:]
+	}
+
+	name = strings.Trim(name, "/")
+	return strings.TrimSuffix(name, ".git")
+}
+
+// relativeFolder returns the path of the project relative to the repository root.
+func relativeFolder(root string, projectPath string) (string, error) {
+	root, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", err
+	}
+	projectPath, err = filepath.Abs(projectPath)
+	if err != nil {
+		return "", err
+	}
+	projectPath, err = filepath.EvalSymlinks(projectPath)
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(root, projectPath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// clean converts the name into a valid fs.FS path.
+func clean(name string) string {
+	cleaned := path.Clean("/" + filepath.ToSlash(name))
+	if cleaned == "/" {
+		return "."
+	}
+	return cleaned[1:]
+}
+
+func (f FS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	info, err := f.stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	if info.IsDir() {
+		entries, err := f.ReadDir(name)
+		if err != nil {
+			return nil, err
+		}
+		return &dir{info: info, entries: entries}, nil
+	}
+
+	gitFile, err := f.tree.File(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	reader, err := gitFile.Reader()
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	defer reader.Close()
+
+	// The whole file is read, so that it can be used with Seek and ReadAt (e.g. by http.FileServer).
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	return &file{info: info, Reader: bytes.NewReader(content)}, nil
+}
+
+func (f FS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+
+	info, err := f.stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return info, nil
+}
+
+func (f FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	tree := f.tree
+	if name != "." {
+		var err error
+		tree, err = f.tree.Tree(name)
+		if err != nil {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+		}
+	}
+
+	entries := make([]fs.DirEntry, 0, len(tree.Entries))
+	for _, entry := range tree.Entries {
+		info, err := f.entryInfo(tree, entry)
+		if err != nil {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+		}
+		entries = append(entries, fs.FileInfoToDirEntry(info))
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+func (f FS) stat(name string) (fileInfo, error) {
+	if name == "." {
+		return fileInfo{name: ".", mode: fs.ModeDir | 0555, modTime: f.modTime}, nil
+	}
+
+	entry, err := f.tree.FindEntry(name)
+	if err != nil {
+		return fileInfo{}, fs.ErrNotExist
+	}
+
+	parent := f.tree
+	if dir := path.Dir(name); dir != "." {
+		parent, err = f.tree.Tree(dir)
+		if err != nil {
+			return fileInfo{}, fs.ErrNotExist
+		}
+	}
+
+	return f.entryInfo(parent, *entry)
+}
+
+func (f FS) entryInfo(parent *object.Tree, entry object.TreeEntry) (fileInfo, error) {
+	info := fileInfo{
+		name:    entry.Name,
+		modTime: f.modTime,
+	}
+
+	switch entry.Mode {
+	case filemode.Dir:
+		info.mode = fs.ModeDir | 0555
+		return info, nil
+	case filemode.Submodule:
+		// The content of submodules is not part of the repository.
+		info.mode = fs.ModeDir | 0555
+		return info, nil
+	case filemode.Symlink:
+		info.mode = fs.ModeSymlink | 0444
+	case filemode.Executable:
+		info.mode = 0555
+	default:
+		info.mode = 0444
+	}
+
+	blob, err := parent.TreeEntryFile(&entry)
+	if err != nil {
+		return fileInfo{}, err
+	}
+	info.size = blob.Size
+	return info, nil
+}
+
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (i fileInfo) Name() string       { return i.name }
+func (i fileInfo) Size() int64        { return i.size }
+func (i fileInfo) Mode() fs.FileMode  { return i.mode }
+func (i fileInfo) ModTime() time.Time { return i.modTime }
+func (i fileInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i fileInfo) Sys() interface{}   { return nil }
+
+type file struct {
+	*bytes.Reader
+	info fileInfo
+}
+
+func (f *file) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *file) Close() error               { return nil }
+
+type dir struct {
+	info    fileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *dir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *dir) Close() error               { return nil }
+
+func (d *dir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: errors.New("is a directory")}
+}
+
+func (d *dir) ReadDir(count int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if count <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(rest) {
+		count = len(rest)
+	}
+	d.offset += count
+	return rest[:count], nil
+}
@@ -0,0 +1,215 @@
+package gitfs
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+// testRepo creates a repository with two commits.
+// The first one is tagged with v1.0.0, the working tree contains uncommitted changes.
+func testRepo(t *testing.T) string {
+	repoPath := t.TempDir()
+	repo, err := git.PlainInit(repoPath, false)
+	assert.NoError(t, err)
+	worktree, err := repo.Worktree()
+	assert.NoError(t, err)
+
+	commit := func(message string, files map[string]string) {
+		for name, content := range files {
+			assert.NoError(t, os.MkdirAll(filepath.Join(repoPath, filepath.Dir(name)), 0775))
+			assert.NoError(t, os.WriteFile(filepath.Join(repoPath, name), []byte(content), 0664))
+		}
+		assert.NoError(t, worktree.AddGlob("."))
+		hash, err := worktree.Commit(message, &git.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)},
+		})
+		assert.NoError(t, err)
+
+		if message == "first" {
+			_, err = repo.CreateTag("v1.0.0", hash, nil)
+			assert.NoError(t, err)
+		}
+	}
+
+	commit("first", map[string]string{
+		"main.go":                  "version 1",
+		"project/doc.go":           "doc 1",
+		"project/templates/a.tpl":  "template 1",
+		"project/templates/b.tpl":  "template",
+		"project/other/nested.txt": "nested",
+	})
+	commit("second", map[string]string{
+		"main.go":        "version 2",
+		"project/doc.go": "doc 2",
+	})
+
+	assert.NoError(t, os.WriteFile(filepath.Join(repoPath, "main.go"), []byte("not committed"), 0664))
+	return repoPath
+}
+
+func TestOpen(t *testing.T) {
+	repoPath := testRepo(t)
+
+	tests := []struct {
+		name        string
+		projectPath string
+		revision    string
+		wantFiles   map[string]string
+		wantErr     error
+	}{
+		{
+			name:        "head",
+			projectPath: repoPath,
+			revision:    "HEAD",
+			wantFiles: map[string]string{
+				"main.go":        "version 2",
+				"project/doc.go": "doc 2",
+			},
+		},
+		{
+			name:        "tag",
+			projectPath: repoPath,
+			revision:    "v1.0.0",
+			wantFiles: map[string]string{
+				"main.go":        "version 1",
+				"project/doc.go": "doc 1",
+			},
+		},
+		{
+			name:        "relative revision",
+			projectPath: repoPath,
+			revision:    "HEAD~1",
+			wantFiles: map[string]string{
+				"main.go": "version 1",
+			},
+		},
+		{
+			name:        "project in a sub folder",
+			projectPath: filepath.Join(repoPath, "project"),
+			revision:    "v1.0.0",
+			wantFiles: map[string]string{
+				"doc.go":          "doc 1",
+				"templates/a.tpl": "template 1",
+			},
+		},
+		{
+			name:        "unknown revision",
+			projectPath: repoPath,
+			revision:    "v2.0.0",
+			wantErr:     ErrRevisionNotFound,
+		},
+		{
+			name:        "no repository",
+			projectPath: t.TempDir(),
+			revision:    "HEAD",
+			wantErr:     ErrNotRepository,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Open(tt.projectPath, tt.revision)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+
+			for name, want := range tt.wantFiles {
+				content, err := fs.ReadFile(got, name)
+				assert.NoError(t, err)
+				assert.Equal(t, want, string(content))
+			}
+		})
+	}
+}
+
+func TestFS(t *testing.T) {
+	repoPath := testRepo(t)
+	gitFS, err := Open(repoPath, "HEAD")
+	assert.NoError(t, err)
+
+	assert.NoError(t, fstest.TestFS(gitFS, "main.go", "project/doc.go", "project/templates/a.tpl", "project/other/nested.txt"))
+
+	info, err := gitFS.Stat("project/doc.go")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), info.Size())
+	assert.Equal(t, time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC), info.ModTime().UTC())
+}
+
+func TestNew(t *testing.T) {
+	repoPath := testRepo(t)
+	projectFS, err := New(filepath.Join(repoPath, "project"), "HEAD")
+	assert.NoError(t, err)
+
+	// The paths used by afero.BasePathFs work as well.
+	templateFS := afero.NewBasePathFs(projectFS, "templates")
+	content, err := afero.ReadFile(templateFS, "/a.tpl")
+	assert.NoError(t, err)
+	assert.Equal(t, "template 1", string(content))
+
+	var files []string
+	err = afero.Walk(projectFS, ".", func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			files = append(files, filepath.ToSlash(path))
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"doc.go", "other/nested.txt", "templates/a.tpl", "templates/b.tpl"}, files)
+
+	assert.Error(t, afero.WriteFile(projectFS, "new.txt", []byte("new"), 0664))
+}
+
+func TestResolve(t *testing.T) {
+	repoPath := testRepo(t)
+
+	repo, err := git.PlainOpen(repoPath)
+	assert.NoError(t, err)
+	head, err := repo.Head()
+	assert.NoError(t, err)
+
+	got, err := Resolve(filepath.Join(repoPath, "project"), "HEAD")
+	assert.NoError(t, err)
+	assert.Equal(t, Revision{Commit: head.Hash().String(), Folder: "project"}, got)
+
+	_, err = repo.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{"git@github.com:owner/name.git"}})
+	assert.NoError(t, err)
+
+	got, err = Resolve(repoPath, "HEAD")
+	assert.NoError(t, err)
+	assert.Equal(t, Revision{Commit: head.Hash().String(), Repo: "owner/name", Folder: "."}, got)
+
+	_, err = Resolve(repoPath, "v2.0.0")
+	assert.ErrorIs(t, err, ErrRevisionNotFound)
+}
+
+func Test_repoName(t *testing.T) {
+	tests := []struct {
+		remoteURL string
+		want      string
+	}{
+		{remoteURL: "https://github.com/owner/name.git", want: "owner/name"},
+		{remoteURL: "https://git.example.com/group/sub/name", want: "group/sub/name"},
+		{remoteURL: "ssh://git@git.example.com:2222/owner/name.git", want: "owner/name"},
+		{remoteURL: "git@github.com:owner/name.git", want: "owner/name"},
+		{remoteURL: "/local/path/name.git", want: "local/path/name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.remoteURL, func(t *testing.T) {
+			assert.Equal(t, tt.want, repoName(tt.remoteURL))
+		})
+	}
+}
@@ -12,7 +12,9 @@ require (
 )
 
 require (
-	github.com/alecthomas/chroma v0.10.0 // indirect
+	github.com/alecthomas/chroma v0.10.0
+	github.com/go-git/go-git/v5 v5.8.1
+	github.com/pmezard/go-difflib v1.0.0
 	github.com/stretchr/testify v1.8.4
 	golang.org/x/text v0.11.0
 )
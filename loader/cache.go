@@ -0,0 +1,206 @@
+package loader
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"path"
+	"sync"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/core/tag"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/diagnostic"
+	"github.com/spf13/afero"
+)
+
+// CacheFolder is the folder in the project root which contains the scan cache.
+const CacheFolder = ".atwhy-cache"
+
+// cacheFile is the file inside of the CacheFolder which contains the results of the last scan.
+const cacheFile = "scan.json"
+
+// cacheVersion has to be increased each time the format of the cache
+// or the results of the finder change.
+const cacheVersion = 2
+
+// @WHY readme_cache
+// Pass `--cache` (or `cache: true` in the config file) to keep the found tags
+// of all files in the folder `.atwhy-cache` of the project.
+// The next runs (and the reloads of `atwhy serve`) only scan the files which have changed since then.
+// The folder ignores itself for git and atwhy, so you do not have to add it to your ignore files.
+// The cache is dropped automatically if the comment configuration changes or a new atwhy version needs a different format.
+
+// ReportingTagFinder is a TagFinder which reports its diagnostics to the given reporter.
+// The Cache needs it to store the diagnostics of a file together with its tags.
+type ReportingTagFinder interface {
+	TagFinder
+	FindAndReport(filename string, reader io.Reader, reporter diagnostic.Reporter) (tags []tag.Raw, err error)
+}
+
+// Cache keeps the results of the scanned files on the disk.
+// A file is only scanned again if its size, modification time and content hash changed.
+// It is only used with a ReportingTagFinder.
+type Cache struct {
+	// Key identifies all settings which change the results of the finder (e.g. the comment configuration).
+	// The whole cache is dropped if it changes.
+	Key string
+
+	mutex sync.Mutex
+
+	// entries is nil if the cache was not read yet.
+	entries map[string]cacheEntry
+}
+
+type cacheData struct {
+	Version int                   `json:"version"`
+	Key     string                `json:"key"`
+	Files   map[string]cacheEntry `json:"files"`
+}
+
+type cacheEntry struct {
+	Size        int64                   `json:"size"`
+	ModTime     int64                   `json:"modTime"`
+	Hash        string                  `json:"hash"`
+	Tags        []tag.Raw               `json:"tags,omitempty"`
+	Diagnostics []diagnostic.Diagnostic `json:"diagnostics,omitempty"`
+}
+
+// cacheResult is the scan result of a single file.
+type cacheResult struct {
+	entry cacheEntry
+
+	// changed is true if the entry has to be written into the cache.
+	changed bool
+}
+
+// read returns the cached entries.
+// They are read from the disk only once, a missing, invalid or outdated cache is just empty.
+func (c *Cache) read(filesystem afero.Fs) map[string]cacheEntry {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.entries != nil {
+		return c.entries
+	}
+
+	c.entries = make(map[string]cacheEntry)
+
+	content, err := afero.ReadFile(filesystem, path.Join(CacheFolder, cacheFile))
+	if err != nil {
+		return c.entries
+	}
+
+	var data cacheData
+	if err := json.Unmarshal(content, &data); err != nil || data.Version != cacheVersion || data.Key != c.Key {
+		return c.entries
+	}
+
+	if data.Files != nil {
+		c.entries = data.Files
+	}
+	return c.entries
+}
+
+// update replaces all entries by the given ones and writes them to the disk if anything changed.
+// Files which are not part of the results anymore are removed from the cache.
+func (c *Cache) update(filesystem afero.Fs, files []string, results []cacheResult) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	changed := len(files) != len(c.entries)
+	entries := make(map[string]cacheEntry, len(files))
+	for i, file := range files {
+		entries[file] = results[i].entry
+		if _, ok := c.entries[file]; !ok || results[i].changed {
+			changed = true
+		}
+	}
+	c.entries = entries
+
+	if !changed {
+		return nil
+	}
+
+	content, err := json.Marshal(cacheData{
+		Version: cacheVersion,
+		Key:     c.Key,
+		Files:   entries,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := filesystem.MkdirAll(CacheFolder, 0775); err != nil {
+		return err
+	}
+
+	// The cache must neither be scanned nor committed.
+	for _, ignoreFile := range []string{IgnoreFile, ".gitignore"} {
+		err := afero.WriteFile(filesystem, path.Join(CacheFolder, ignoreFile), []byte("*\n"), 0664)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Write to a temporary file first, so that other processes never read a half written cache.
+	tmp := path.Join(CacheFolder, cacheFile+".tmp")
+	if err := afero.WriteFile(filesystem, tmp, content, 0664); err != nil {
+		return err
+	}
+	return filesystem.Rename(tmp, path.Join(CacheFolder, cacheFile))
+}
+
+// find returns the cached result of the file or scans it if it has changed.
+// The diagnostics of the file are always reported to the reporter.
+func (c *Cache) find(filesystem afero.Fs, finder ReportingTagFinder, reporter diagnostic.Reporter, entries map[string]cacheEntry, file string) (cacheResult, error) {
+	result, err := c.lookup(filesystem, finder, entries, file)
+	if err != nil {
+		return cacheResult{}, err
+	}
+
+	for _, d := range result.entry.Diagnostics {
+		diagnostic.Report(reporter, d)
+	}
+	return result, nil
+}
+
+func (c *Cache) lookup(filesystem afero.Fs, finder ReportingTagFinder, entries map[string]cacheEntry, file string) (cacheResult, error) {
+	info, err := filesystem.Stat(file)
+	if err != nil {
+		return cacheResult{}, err
+	}
+
+	cached, found := entries[file]
+	if found && cached.Size == info.Size() && cached.ModTime == info.ModTime().UnixNano() {
+		return cacheResult{entry: cached}, nil
+	}
+
+	content, err := afero.ReadFile(filesystem, file)
+	if err != nil {
+		return cacheResult{}, err
+	}
+
+	hash := sha256.Sum256(content)
+	entry := cacheEntry{
+		Size:    info.Size(),
+		ModTime: info.ModTime().UnixNano(),
+		Hash:    hex.EncodeToString(hash[:]),
+	}
+
+	// Only the modification time changed (e.g. by a checkout), so the old results are still valid.
+	if found && cached.Hash == entry.Hash {
+		entry.Tags = cached.Tags
+		entry.Diagnostics = cached.Diagnostics
+		return cacheResult{entry: entry, changed: true}, nil
+	}
+
+	diagnostics := &diagnostic.Collector{}
+	entry.Tags, err = finder.FindAndReport(file, bytes.NewReader(content), diagnostics)
+	if err != nil {
+		return cacheResult{}, err
+	}
+	entry.Diagnostics = diagnostics.Diagnostics()
+
+	return cacheResult{entry: entry, changed: true}, nil
+}
@@ -0,0 +1,107 @@
+package loader
+
+import (
+	"io"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/core/tag"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/diagnostic"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingFinder reports one diagnostic per file and remembers which files were scanned.
+type countingFinder struct {
+	fakeJSONFinder
+
+	mutex   sync.Mutex
+	scanned []string
+}
+
+func (f *countingFinder) FindAndReport(filename string, reader io.Reader, reporter diagnostic.Reporter) ([]tag.Raw, error) {
+	f.mutex.Lock()
+	f.scanned = append(f.scanned, filename)
+	f.mutex.Unlock()
+
+	diagnostic.Report(reporter, diagnostic.Diagnostic{
+		Severity: diagnostic.SeverityWarning,
+		Code:     "scanned",
+		File:     filename,
+	})
+	return f.Find(filename, reader)
+}
+
+func TestFile_Load_cache(t *testing.T) {
+	memFS := testFs()
+	want := append(testFileMainGo.Tags, testFileRunSh.Tags...)
+	wantFiles := []string{".atwhyignore", "main.go", "run.sh"}
+	wantDiagnostics := []diagnostic.Diagnostic{
+		{Severity: diagnostic.SeverityWarning, Code: "scanned", File: ".atwhyignore"},
+		{Severity: diagnostic.SeverityWarning, Code: "scanned", File: "main.go"},
+		{Severity: diagnostic.SeverityWarning, Code: "scanned", File: "run.sh"},
+	}
+
+	// load uses a new Cache each time to read it from the disk.
+	load := func(t *testing.T, key string) []string {
+		finder := &countingFinder{}
+		diagnostics := &diagnostic.Collector{}
+		fl := File{
+			FS:       memFS,
+			Cache:    &Cache{Key: key},
+			Reporter: diagnostics,
+		}
+
+		got, err := fl.Load(finder)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got)
+		assert.Equal(t, wantDiagnostics, diagnostics.Diagnostics())
+		return finder.scanned
+	}
+
+	t.Run("scan all files without cache", func(t *testing.T) {
+		assert.ElementsMatch(t, wantFiles, load(t, "key"))
+
+		exists, err := afero.Exists(memFS, path.Join(CacheFolder, cacheFile))
+		assert.NoError(t, err)
+		assert.True(t, exists)
+	})
+
+	t.Run("use the cache for unchanged files", func(t *testing.T) {
+		assert.Empty(t, load(t, "key"))
+	})
+
+	t.Run("use the cache if only the modification time changed", func(t *testing.T) {
+		later := time.Now().Add(time.Hour)
+		assert.NoError(t, memFS.Chtimes("main.go", later, later))
+		assert.Empty(t, load(t, "key"))
+	})
+
+	t.Run("scan changed files", func(t *testing.T) {
+		changed := testFileMainGo
+		changed.Tags = append([]tag.Raw{}, testFileMainGo.Tags...)
+		changed.Tags[0].Value = "changed"
+		assert.NoError(t, afero.WriteFile(memFS, "main.go", changed.toJSON(), 0777))
+		defer func() {
+			assert.NoError(t, afero.WriteFile(memFS, "main.go", testFileMainGo.toJSON(), 0777))
+		}()
+
+		finder := &countingFinder{}
+		got, err := File{FS: memFS, Cache: &Cache{Key: "key"}}.Load(finder)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"main.go"}, finder.scanned)
+		assert.Equal(t, append(changed.Tags, testFileRunSh.Tags...), got)
+	})
+
+	t.Run("scan all files if the key changed", func(t *testing.T) {
+		assert.ElementsMatch(t, wantFiles, load(t, "other"))
+	})
+
+	t.Run("the cache is not scanned", func(t *testing.T) {
+		files, err := File{FS: memFS}.files()
+		assert.NoError(t, err)
+		assert.Equal(t, wantFiles, files)
+	})
+}
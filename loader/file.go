@@ -1,38 +1,149 @@
 package loader
 
 import (
+	"errors"
 	"io"
 	"io/fs"
+	"path"
+	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
 
 	"github.com/Tiffinger-Thiel-GmbH/atwhy/core/tag"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/diagnostic"
 
 	"github.com/aligator/nogo"
 	"github.com/spf13/afero"
 )
 
+// TagFinder reads the tags of a single file.
+// File calls Find from several goroutines at the same time,
+// so implementations have to be safe for concurrent use.
 type TagFinder interface {
 	Find(filename string, reader io.Reader) (tags []tag.Raw, err error)
 }
 
+// IgnoreFile is the name of the files with the rules for the files which should not be scanned.
+const IgnoreFile = ".atwhyignore"
+
 type File struct {
 	FS             afero.Fs
 	FileExtensions []string
+
+	// Workers is the number of files which are scanned at the same time.
+	// Default is runtime.NumCPU().
+	Workers int
+
+	// Cache is optional. If it is set, only changed files are scanned again.
+	Cache *Cache
+
+	// Reporter receives the cached diagnostics of the files which are not scanned again.
+	// It should be the same Reporter the finder uses.
+	Reporter diagnostic.Reporter
 }
 
+// Load scans all files of the FS which are not ignored.
+// The tags are returned in the order of the files (sorted by path)
+// and in the order of their occurrence inside of each file.
 func (fl File) Load(finder TagFinder) ([]tag.Raw, error) {
+	files, err := fl.files()
+	if err != nil {
+		return nil, err
+	}
+
+	workers := fl.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(files) {
+		workers = len(files)
+	}
+
+	reportingFinder, canCache := finder.(ReportingTagFinder)
+	useCache := fl.Cache != nil && canCache
+	var cached map[string]cacheEntry
+	var cacheResults []cacheResult
+	if useCache {
+		cached = fl.Cache.read(fl.FS)
+		cacheResults = make([]cacheResult, len(files))
+	}
+
+	// Each worker writes only to the indices it got, so the results keep the order of the files.
+	results := make([][]tag.Raw, len(files))
+	errs := make([]error, len(files))
+
+	jobs := make(chan int)
+
+	// failed stops passing new files to the workers after the first error.
+	failed := make(chan struct{})
+	var failOnce sync.Once
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if useCache {
+					cacheResults[i], errs[i] = fl.Cache.find(fl.FS, reportingFinder, fl.Reporter, cached, files[i])
+					results[i] = cacheResults[i].entry.Tags
+				} else {
+					results[i], errs[i] = fl.find(finder, files[i])
+				}
+				if errs[i] != nil {
+					failOnce.Do(func() { close(failed) })
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for i := range files {
+			select {
+			case jobs <- i:
+			case <-failed:
+				return
+			}
+		}
+	}()
+
+	wg.Wait()
+
 	allTags := make([]tag.Raw, 0)
+	for i := range files {
+		// The files are passed in order, so all files before a failed one are
+		// scanned and this always returns the error of the first failing file.
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		allTags = append(allTags, results[i]...)
+	}
+
+	if useCache {
+		err := fl.Cache.update(fl.FS, files, cacheResults)
+		if err != nil {
+			return nil, err
+		}
+	}
 
+	return allTags, nil
+}
+
+// files returns the paths of all files which have to be scanned.
+func (fl File) files() ([]string, error) {
 	sysfs := afero.NewIOFS(fl.FS)
 
 	// @WHY readme_ignore
 	// You can create a `.atwhyignore` file which just follows the `.gitignore` syntax.
 	// (If you find an inconsistency with the git-handling, please report it [here](https://github.com/aligator/NoGo/issues).)
 	n := nogo.New(nogo.DotGitRule)
-	if err := n.AddFromFS(sysfs, ".atwhyignore"); err != nil {
+	if err := n.AddFromFS(sysfs, IgnoreFile); err != nil {
 		return nil, err
 	}
 
+	var files []string
 	err := afero.Walk(fl.FS, ".", func(path string, info fs.FileInfo, err error) error {
 		if ok, err := n.WalkFunc(sysfs, path, info.IsDir(), err); !ok {
 			return err
@@ -56,22 +167,59 @@ func (fl File) Load(finder TagFinder) ([]tag.Raw, error) {
 			}
 		}
 
-		file, err := fl.FS.Open(path)
-		if err != nil {
-			return err
-		}
-		defer file.Close()
-
-		tags, err := finder.Find(path, file)
-		if err != nil {
-			return err
-		}
-		allTags = append(allTags, tags...)
+		files = append(files, path)
 		return nil
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	return allTags, nil
+	return files, nil
+}
+
+func (fl File) find(finder TagFinder, path string) ([]tag.Raw, error) {
+	file, err := fl.FS.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return finder.Find(path, file)
+}
+
+// Ignored checks if the file or folder with the given path is ignored by the IgnoreFile rules
+// (or is inside of the .git folder) in the same way as Load does.
+// Only the ignore files of the parent folders are read.
+func Ignored(fsys afero.Fs, name string, isDir bool) (bool, error) {
+	sysfs := afero.NewIOFS(fsys)
+	n := nogo.New(nogo.DotGitRule)
+
+	name = strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(name)), "/")
+	if name == "" {
+		return false, nil
+	}
+
+	// Check each parent folder the same way as the walk of Load does, starting at the root.
+	folder := "."
+	for _, segment := range strings.Split(name, "/") {
+		if folder != "." {
+			if match, _ := n.MatchWithoutParents(folder, true); match {
+				return true, nil
+			}
+		}
+
+		// Load a maybe existing ignore file if it is not itself ignored.
+		ignoreFile := path.Join(folder, IgnoreFile)
+		if match, _ := n.MatchWithoutParents(ignoreFile, false); !match {
+			err := n.AddFile(sysfs, ignoreFile)
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return false, err
+			}
+		}
+
+		folder = path.Join(folder, segment)
+	}
+
+	match, _ := n.MatchWithoutParents(name, isDir)
+	return match, nil
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/Tiffinger-Thiel-GmbH/atwhy/core/tag"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
@@ -149,3 +150,63 @@ func TestFile_Load(t *testing.T) {
 		})
 	}
 }
+
+func TestFile_Load_order(t *testing.T) {
+	memFS := afero.NewMemMapFs()
+	var want []tag.Raw
+	for i := 0; i < 50; i++ {
+		file := fakeFile{
+			Tags: []tag.Raw{
+				{Type: tag.TypeDoc, Placeholder: "first", Filename: fmt.Sprintf("dir%02d/file.go", i), Line: 1, Value: "value"},
+				{Type: tag.TypeDoc, Placeholder: "second", Filename: fmt.Sprintf("dir%02d/file.go", i), Line: 5, Value: "value"},
+			},
+		}
+		_ = afero.WriteFile(memFS, fmt.Sprintf("dir%02d/file.go", i), file.toJSON(), 0777)
+		want = append(want, file.Tags...)
+	}
+
+	for _, workers := range []int{0, 1, 4, 100} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			fl := File{
+				FS:      memFS,
+				Workers: workers,
+			}
+			got, err := fl.Load(fakeJSONFinder{})
+			assert.NoError(t, err)
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestIgnored(t *testing.T) {
+	memFS := afero.NewMemMapFs()
+	_ = afero.WriteFile(memFS, ".atwhyignore", []byte("/ignored.go\nsecret/\n"), 0777)
+	_ = afero.WriteFile(memFS, "sub/.atwhyignore", []byte("*.log\n"), 0777)
+	_ = afero.WriteFile(memFS, "sub/secret/.atwhyignore", []byte("!*\n"), 0777)
+
+	tests := []struct {
+		name  string
+		isDir bool
+		want  bool
+	}{
+		{name: "main.go", want: false},
+		{name: "ignored.go", want: true},
+		{name: ".git", isDir: true, want: true},
+		{name: ".git/config", want: true},
+		{name: "secret", isDir: true, want: true},
+		{name: "secret/key.txt", want: true},
+		{name: "sub/run.log", want: true},
+		{name: "run.log", want: false},
+		{name: "sub/ignored.go", want: false},
+		// The ignore files of ignored folders are not used.
+		{name: "sub/secret/key.txt", want: true},
+		{name: "/sub/main.go", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Ignored(memFS, tt.name, tt.isDir)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"sync"
+)
+
+var ErrMissingContentLength = errors.New("the message has no Content-Length header")
+
+// The error codes of JSON-RPC used by the Server.
+const (
+	codeParseError     = -32700
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+// request is a JSON-RPC request or notification received from the client.
+// Notifications have no ID.
+type request struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *responseError  `json:"error,omitempty"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *responseError) Error() string {
+	return e.Message
+}
+
+type notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+// conn reads and writes the messages of the base protocol:
+// a Content-Length header, an empty line and the json content.
+type conn struct {
+	reader *textproto.Reader
+	writer io.Writer
+	mutex  sync.Mutex
+}
+
+func newConn(reader io.Reader, writer io.Writer) *conn {
+	return &conn{
+		reader: textproto.NewReader(bufio.NewReader(reader)),
+		writer: writer,
+	}
+}
+
+// read the next message.
+// It returns io.EOF if the client has closed the connection.
+func (c *conn) read() (request, error) {
+	header, err := c.reader.ReadMIMEHeader()
+	if err != nil {
+		return request{}, err
+	}
+
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return request{}, ErrMissingContentLength
+	}
+
+	content := make([]byte, length)
+	_, err = io.ReadFull(c.reader.R, content)
+	if err != nil {
+		return request{}, err
+	}
+
+	var req request
+	err = json.Unmarshal(content, &req)
+	if err != nil {
+		return request{}, &responseError{Code: codeParseError, Message: err.Error()}
+	}
+	return req, nil
+}
+
+// reply sends the result or the error of a request.
+func (c *conn) reply(id json.RawMessage, result interface{}, err error) error {
+	res := response{JSONRPC: "2.0", ID: id}
+	if id == nil {
+		res.ID = json.RawMessage("null")
+	}
+
+	if err != nil {
+		var resErr *responseError
+		if !errors.As(err, &resErr) {
+			resErr = &responseError{Code: codeInternalError, Message: err.Error()}
+		}
+		res.Error = resErr
+	} else {
+		res.Result, err = json.Marshal(result)
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.write(res)
+}
+
+// notify sends a notification to the client.
+func (c *conn) notify(method string, params interface{}) error {
+	return c.write(notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (c *conn) write(message interface{}) error {
+	content, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	_, err = fmt.Fprintf(c.writer, "Content-Length: %d\r\n\r\n%s", len(content), content)
+	return err
+}
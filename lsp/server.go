@@ -0,0 +1,667 @@
+// Package lsp implements a language server for the tags and templates of a project.
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/core"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/core/tag"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/diagnostic"
+	mdTemplate "github.com/Tiffinger-Thiel-GmbH/atwhy/template"
+	"github.com/spf13/afero"
+)
+
+// templateExt is the extension of the templates.
+const templateExt = ".tpl.md"
+
+// completionRegex matches an incomplete reference at the end of the text before the cursor.
+// The text cannot be parsed as template while it is incomplete.
+var completionRegex = regexp.MustCompile(`(?:\.Tag\.|index \$?\.Tag "|\.Group "|\.Tags\.WithPrefix ")([a-z_0-9]*)$`)
+
+// Server is a language server for the tags and templates of one project.
+// It serves one client over a reader and a writer, e.g. stdin and stdout.
+//
+// The project is loaded again with the open documents of the client as soon as
+// a document is opened, saved or closed, or a request needs the changed documents.
+type Server struct {
+	// ProjectPath is the absolute path of the project.
+	ProjectPath string
+
+	// TemplatesFolder is the folder of the templates relative to the project.
+	TemplatesFolder string
+
+	// New creates the AtWhy which loads the project.
+	// The overlay contains the open documents and has to be passed as core.Options.Overlay.
+	New func(overlay afero.Fs) (core.AtWhy, error)
+
+	// Rules change the severities of the published diagnostics.
+	// It may be nil.
+	Rules diagnostic.Rules
+
+	// Log receives the errors which cannot be sent to the client.
+	// It may be nil.
+	Log io.Writer
+
+	conn *conn
+
+	// documents contains the text of the open documents by their path relative to the project.
+	documents map[string]string
+	overlay   afero.Fs
+
+	// dirty is true if the documents have changed since the last load.
+	dirty bool
+
+	// tags and templates are the results of the last successful load.
+	// They are kept if a later load fails, e.g. while a template is edited.
+	tags      []tag.Tag
+	templates []mdTemplate.Markdown
+
+	// published contains the files which currently have diagnostics in the client.
+	published map[string]bool
+}
+
+// symbol is a reference to a placeholder (or to all placeholders with a prefix) in a document.
+type symbol struct {
+	placeholder string
+	group       bool
+	rng         Range
+}
+
+// matches checks if the tag is referenced by the symbol.
+func (s symbol) matches(t tag.Tag) bool {
+	if s.group {
+		return strings.HasPrefix(t.Placeholder(), s.placeholder)
+	}
+	return t.Placeholder() == s.placeholder
+}
+
+// Serve handles the messages of the client until it sends exit or closes the connection.
+func (s *Server) Serve(reader io.Reader, writer io.Writer) error {
+	s.conn = newConn(reader, writer)
+	s.documents = make(map[string]string)
+	s.overlay = afero.NewMemMapFs()
+	s.published = make(map[string]bool)
+	s.dirty = true
+
+	for {
+		req, err := s.conn.read()
+		var resErr *responseError
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if errors.As(err, &resErr) {
+			if err := s.conn.reply(nil, nil, err); err != nil {
+				return err
+			}
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if req.Method == "exit" {
+			return nil
+		}
+
+		result, err := s.handle(req)
+		if req.ID == nil {
+			// Notifications have no response.
+			if err != nil {
+				s.logf("%v: %v", req.Method, err)
+			}
+			continue
+		}
+
+		if err := s.conn.reply(req.ID, result, err); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(req request) (interface{}, error) {
+	switch req.Method {
+	case "initialize":
+		result := InitializeResult{
+			Capabilities: ServerCapabilities{
+				TextDocumentSync: TextDocumentSyncOptions{
+					OpenClose: true,
+					Change:    textDocumentSyncKindFull,
+					Save:      true,
+				},
+				CompletionProvider: CompletionOptions{TriggerCharacters: []string{".", `"`}},
+				HoverProvider:      true,
+				DefinitionProvider: true,
+				ReferencesProvider: true,
+			},
+		}
+		result.ServerInfo.Name = "atwhy"
+		return result, nil
+	case "initialized", "textDocument/didSave", "workspace/didChangeWatchedFiles":
+		s.dirty = true
+		return nil, s.refresh()
+	case "shutdown":
+		return nil, nil
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if err := decode(req, &params); err != nil {
+			return nil, err
+		}
+		if err := s.setDocument(params.TextDocument.URI, params.TextDocument.Text); err != nil {
+			return nil, err
+		}
+		return nil, s.refresh()
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if err := decode(req, &params); err != nil {
+			return nil, err
+		}
+		if len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+		// Only full changes are supported, so the last one contains the whole text.
+		return nil, s.setDocument(params.TextDocument.URI, params.ContentChanges[len(params.ContentChanges)-1].Text)
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if err := decode(req, &params); err != nil {
+			return nil, err
+		}
+		if err := s.removeDocument(params.TextDocument.URI); err != nil {
+			return nil, err
+		}
+		return nil, s.refresh()
+	case "textDocument/completion":
+		var params TextDocumentPositionParams
+		if err := decode(req, &params); err != nil {
+			return nil, err
+		}
+		return s.completion(params), nil
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		if err := decode(req, &params); err != nil {
+			return nil, err
+		}
+		return s.definition(params), nil
+	case "textDocument/references":
+		var params ReferenceParams
+		if err := decode(req, &params); err != nil {
+			return nil, err
+		}
+		return s.references(params), nil
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		if err := decode(req, &params); err != nil {
+			return nil, err
+		}
+		return s.hover(params), nil
+	}
+
+	if req.ID == nil {
+		// Unknown notifications (e.g. $/cancelRequest) can be ignored.
+		return nil, nil
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: "method not found: " + req.Method}
+}
+
+// decode the params of the request.
+func decode(req request, params interface{}) error {
+	err := json.Unmarshal(req.Params, params)
+	if err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func (s *Server) logf(format string, args ...interface{}) {
+	if s.Log != nil {
+		fmt.Fprintf(s.Log, format+"\n", args...)
+	}
+}
+
+// setDocument stores the text of an open document in the overlay.
+// Documents outside of the project are ignored.
+func (s *Server) setDocument(uri string, text string) error {
+	file, ok := s.file(uri)
+	if !ok {
+		return nil
+	}
+
+	s.documents[file] = text
+	s.dirty = true
+	return afero.WriteFile(s.overlay, filepath.Join("/", file), []byte(text), 0664)
+}
+
+// removeDocument removes a closed document from the overlay.
+func (s *Server) removeDocument(uri string) error {
+	file, ok := s.file(uri)
+	if !ok {
+		return nil
+	}
+
+	delete(s.documents, file)
+	s.dirty = true
+	err := s.overlay.Remove(filepath.Join("/", file))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// refresh loads the project again and publishes the diagnostics if the documents have changed.
+// Errors of the load are logged, so that the requests can still use the last successful load.
+func (s *Server) refresh() error {
+	if !s.dirty {
+		return nil
+	}
+	s.dirty = false
+
+	atwhy, err := s.New(s.overlay)
+	if err != nil {
+		return err
+	}
+
+	diagnostics, err := s.load(&atwhy)
+	if err != nil {
+		return err
+	}
+
+	return s.publish(s.Rules.Apply(diagnostics))
+}
+
+// load all tags and templates and return the diagnostics.
+// Invalid tags and templates are returned as diagnostics as well.
+func (s *Server) load(atwhy *core.AtWhy) ([]diagnostic.Diagnostic, error) {
+	var d diagnostic.Diagnostic
+
+	tags, err := atwhy.LoadTags()
+	if errors.As(err, &d) {
+		return append(atwhy.Diagnostics.Diagnostics(), d), nil
+	} else if err != nil {
+		return nil, err
+	}
+	s.tags = tags
+
+	templates, err := atwhy.TemplateLoader.Load(tags)
+	if errors.As(err, &d) {
+		return append(atwhy.Diagnostics.Diagnostics(), d), nil
+	} else if err != nil {
+		return nil, err
+	}
+	s.templates = templates
+
+	return atwhy.Diagnostics.Diagnostics(), nil
+}
+
+// publish sends the diagnostics of each file.
+// Files which had diagnostics before but do not have them anymore get an empty list.
+func (s *Server) publish(diagnostics []diagnostic.Diagnostic) error {
+	files := make(map[string][]Diagnostic)
+	for file := range s.published {
+		files[file] = []Diagnostic{}
+	}
+
+	for _, d := range diagnostics {
+		lines := s.lines(d.File)
+		start := position(lines, d.Line, d.Column)
+		end := start
+		if d.Line > 0 && d.Line <= len(lines) {
+			end.Character = utf16Column(lines[d.Line-1], len(lines[d.Line-1]))
+		}
+
+		severity := diagnosticSeverityWarning
+		if d.Severity == diagnostic.SeverityError {
+			severity = diagnosticSeverityError
+		}
+
+		files[d.File] = append(files[d.File], Diagnostic{
+			Range:    Range{Start: start, End: end},
+			Severity: severity,
+			Code:     d.Code,
+			Source:   "atwhy",
+			Message:  d.Message,
+		})
+	}
+
+	names := make([]string, 0, len(files))
+	for file := range files {
+		names = append(names, file)
+	}
+	sort.Strings(names)
+
+	s.published = make(map[string]bool)
+	for _, file := range names {
+		if len(files[file]) > 0 {
+			s.published[file] = true
+		}
+
+		err := s.conn.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+			URI:         s.uri(file),
+			Diagnostics: files[file],
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// completion offers all placeholders inside of incomplete references of templates.
+func (s *Server) completion(params TextDocumentPositionParams) []CompletionItem {
+	s.refreshAndLog()
+
+	items := []CompletionItem{}
+	file, ok := s.file(params.TextDocument.URI)
+	if !ok || !s.isTemplate(file) {
+		return items
+	}
+
+	lines := s.lines(file)
+	if params.Position.Line >= len(lines) {
+		return items
+	}
+	line := lines[params.Position.Line]
+	match := completionRegex.FindStringSubmatch(line[:byteOffset(line, params.Position.Character)])
+	if match == nil {
+		return items
+	}
+
+	seen := make(map[string]bool)
+	for _, t := range s.sortedTags() {
+		if seen[t.Placeholder()] || !strings.HasPrefix(t.Placeholder(), match[1]) {
+			continue
+		}
+		seen[t.Placeholder()] = true
+
+		items = append(items, CompletionItem{
+			Label:         t.Placeholder(),
+			Kind:          completionItemKindField,
+			Detail:        fmt.Sprintf("%v %v:%v", t.Type(), t.File(), t.Line()),
+			Documentation: &MarkupContent{Kind: markupKindMarkdown, Value: t.String()},
+		})
+	}
+
+	return items
+}
+
+// definition returns the locations of the tags referenced at the position.
+func (s *Server) definition(params TextDocumentPositionParams) []Location {
+	s.refreshAndLog()
+
+	locations := []Location{}
+	sym, ok := s.symbolAt(params)
+	if !ok {
+		return locations
+	}
+
+	for _, t := range s.sortedTags() {
+		if sym.matches(t) {
+			locations = append(locations, s.tagLocation(t))
+		}
+	}
+	return locations
+}
+
+// references returns the locations of all template references to the tag at the position.
+func (s *Server) references(params ReferenceParams) []Location {
+	s.refreshAndLog()
+
+	locations := []Location{}
+	sym, ok := s.symbolAt(params.TextDocumentPositionParams)
+	if !ok {
+		return locations
+	}
+
+	if params.Context.IncludeDeclaration {
+		for _, t := range s.sortedTags() {
+			if sym.matches(t) {
+				locations = append(locations, s.tagLocation(t))
+			}
+		}
+	}
+
+	for _, r := range mdTemplate.References(s.templates) {
+		// Dynamic accesses (e.g. {{ range .Tag }}) would match every tag.
+		if r.Group && r.Placeholder == "" {
+			continue
+		}
+
+		var found bool
+		if sym.group {
+			found = r.Group && r.Placeholder == sym.placeholder
+		} else {
+			found = r.Matches(sym.placeholder)
+		}
+		if found {
+			locations = append(locations, s.referenceLocation(r))
+		}
+	}
+
+	return locations
+}
+
+// hover shows the values of the tags referenced at the position.
+func (s *Server) hover(params TextDocumentPositionParams) *Hover {
+	s.refreshAndLog()
+
+	sym, ok := s.symbolAt(params)
+	if !ok {
+		return nil
+	}
+
+	var parts []string
+	for _, t := range s.sortedTags() {
+		if !sym.matches(t) {
+			continue
+		}
+
+		if sym.group {
+			parts = append(parts, fmt.Sprintf("* `%v` (%v:%v)", t.Placeholder(), t.File(), t.Line()))
+		} else {
+			parts = append(parts, fmt.Sprintf("`%v` %v %v:%v\n\n%v", t.Placeholder(), t.Type(), t.File(), t.Line(), t.String()))
+		}
+	}
+
+	var value string
+	switch {
+	case len(parts) == 0 && sym.group:
+		value = "There is no tag starting with `" + sym.placeholder + "`."
+	case len(parts) == 0:
+		value = "The tag `" + sym.placeholder + "` does not exist."
+	case sym.group:
+		value = "Tags starting with `" + sym.placeholder + "`:\n" + strings.Join(parts, "\n")
+	default:
+		value = strings.Join(parts, "\n\n---\n\n")
+	}
+
+	rng := sym.rng
+	return &Hover{
+		Contents: MarkupContent{Kind: markupKindMarkdown, Value: value},
+		Range:    &rng,
+	}
+}
+
+// refreshAndLog refreshes before a request.
+// An error is only logged, as the request can still use the last successful load.
+func (s *Server) refreshAndLog() {
+	if err := s.refresh(); err != nil {
+		s.logf("%v", err)
+	}
+}
+
+// symbolAt returns the placeholder at the position.
+// In templates it is a reference to a tag, in all other files the tag at that line.
+func (s *Server) symbolAt(params TextDocumentPositionParams) (symbol, bool) {
+	file, ok := s.file(params.TextDocument.URI)
+	if !ok {
+		return symbol{}, false
+	}
+
+	if !s.isTemplate(file) {
+		line := params.Position.Line + 1
+		for _, t := range s.tags {
+			if t.File() == file && t.Line() <= line && line <= t.EndLine() {
+				return symbol{placeholder: t.Placeholder(), rng: s.tagLocation(t).Range}, true
+			}
+		}
+		return symbol{}, false
+	}
+
+	// The references are taken from the last successful load of the templates.
+	for _, r := range mdTemplate.References(s.templates) {
+		// Dynamic accesses (e.g. {{ range .Tag }}) have no placeholder.
+		if r.File != file || r.Group && r.Placeholder == "" {
+			continue
+		}
+
+		rng := s.referenceLocation(r).Range
+		pos := params.Position
+		if pos.Line != rng.Start.Line || pos.Character < rng.Start.Character || pos.Character > rng.End.Character {
+			continue
+		}
+
+		return symbol{placeholder: r.Placeholder, group: r.Group, rng: rng}, true
+	}
+
+	return symbol{}, false
+}
+
+// isTemplate checks if the file relative to the project is a template or partial inside of the TemplatesFolder.
+func (s *Server) isTemplate(file string) bool {
+	folder := path.Clean(filepath.ToSlash(s.TemplatesFolder))
+	if folder != "." && !strings.HasPrefix(file, folder+"/") {
+		return false
+	}
+	return strings.HasSuffix(file, templateExt)
+}
+
+// sortedTags returns the tags sorted by their location.
+func (s *Server) sortedTags() []tag.Tag {
+	tags := make([]tag.Tag, len(s.tags))
+	copy(tags, s.tags)
+	sort.SliceStable(tags, func(i, j int) bool {
+		if tags[i].File() != tags[j].File() {
+			return tags[i].File() < tags[j].File()
+		}
+		return tags[i].Line() < tags[j].Line()
+	})
+	return tags
+}
+
+// tagLocation returns the location from the \@WHY to the end of the tag.
+func (s *Server) tagLocation(t tag.Tag) Location {
+	lines := s.lines(t.File())
+	return Location{
+		URI: s.uri(t.File()),
+		Range: Range{
+			Start: position(lines, t.Line(), t.Column()),
+			// The EndColumn is the last character, so the exclusive end is the next column.
+			End: position(lines, t.EndLine(), t.EndColumn()+1),
+		},
+	}
+}
+
+// referenceLocation returns the location of the placeholder of the reference.
+func (s *Server) referenceLocation(r mdTemplate.Reference) Location {
+	lines := s.lines(r.File)
+	start := position(lines, r.Line, r.Column)
+	end := start
+
+	if r.Line > 0 && r.Line <= len(lines) {
+		line := lines[r.Line-1]
+		from := r.Column - 1
+		if from < 0 || from > len(line) {
+			from = 0
+		}
+		if i := strings.Index(line[from:], r.Placeholder); i >= 0 {
+			start.Character = utf16Column(line, from+i)
+			end.Character = utf16Column(line, from+i+len(r.Placeholder))
+		}
+	}
+
+	return Location{URI: s.uri(r.File), Range: Range{Start: start, End: end}}
+}
+
+// lines returns the lines of the file relative to the project.
+// Open documents are used instead of the saved files.
+func (s *Server) lines(file string) []string {
+	text, ok := s.documents[file]
+	if !ok {
+		content, err := os.ReadFile(filepath.Join(s.ProjectPath, filepath.FromSlash(file)))
+		if err != nil {
+			return nil
+		}
+		text = string(content)
+	}
+
+	return strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+}
+
+// file converts the uri into a path relative to the project.
+// It returns false if the uri is not a file of the project.
+func (s *Server) file(uri string) (string, bool) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return "", false
+	}
+
+	rel, err := filepath.Rel(s.ProjectPath, filepath.FromSlash(u.Path))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// uri converts the path relative to the project into a file uri.
+func (s *Server) uri(file string) string {
+	u := url.URL{
+		Scheme: "file",
+		Path:   filepath.ToSlash(filepath.Join(s.ProjectPath, filepath.FromSlash(file))),
+	}
+	return u.String()
+}
+
+// position converts the 1-based line and byte column of atwhy into a Position.
+// Unknown lines and columns (0) are converted to the start of the file or line.
+func position(lines []string, line int, column int) Position {
+	if line < 1 {
+		return Position{}
+	}
+	if column < 1 || line > len(lines) {
+		return Position{Line: line - 1}
+	}
+	return Position{Line: line - 1, Character: utf16Column(lines[line-1], column-1)}
+}
+
+// utf16Column converts the byte offset in the line into UTF-16 code units.
+func utf16Column(line string, offset int) int {
+	if offset > len(line) {
+		offset = len(line)
+	}
+	return len(utf16.Encode([]rune(line[:offset])))
+}
+
+// byteOffset converts the UTF-16 column into a byte offset in the line.
+func byteOffset(line string, column int) int {
+	units := 0
+	for i, r := range line {
+		if units >= column {
+			return i
+		}
+
+		units++
+		if r >= 0x10000 {
+			// Surrogate pair.
+			units++
+		}
+	}
+	return len(line)
+}
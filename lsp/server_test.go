@@ -0,0 +1,319 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/core"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/diagnostic"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/finder"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/generator"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testMessage is any message sent by the server.
+type testMessage struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *responseError  `json:"error"`
+}
+
+type testClient struct {
+	t        *testing.T
+	writer   io.Writer
+	messages chan testMessage
+	nextID   int
+
+	// notifications contains all notifications received since the last call of takeNotifications.
+	notifications []testMessage
+}
+
+func (c *testClient) send(message interface{}) {
+	content, err := json.Marshal(message)
+	require.NoError(c.t, err)
+	_, err = fmt.Fprintf(c.writer, "Content-Length: %d\r\n\r\n%s", len(content), content)
+	require.NoError(c.t, err)
+}
+
+// readMessages reads all messages of the server in the background,
+// as the pipes block the server until the client reads its messages.
+func (c *testClient) readMessages(reader io.Reader) {
+	defer close(c.messages)
+	r := textproto.NewReader(bufio.NewReader(reader))
+	for {
+		header, err := r.ReadMIMEHeader()
+		if err != nil {
+			return
+		}
+		length, err := strconv.Atoi(header.Get("Content-Length"))
+		if err != nil {
+			return
+		}
+
+		content := make([]byte, length)
+		if _, err := io.ReadFull(r.R, content); err != nil {
+			return
+		}
+
+		var message testMessage
+		if err := json.Unmarshal(content, &message); err != nil {
+			return
+		}
+		c.messages <- message
+	}
+}
+
+func (c *testClient) receive() testMessage {
+	select {
+	case message, ok := <-c.messages:
+		require.True(c.t, ok, "the server has closed the connection")
+		return message
+	case <-time.After(5 * time.Second):
+		require.FailNow(c.t, "no message from the server")
+		return testMessage{}
+	}
+}
+
+// request sends a request and decodes the result of its response into result.
+// All notifications received before the response are collected.
+func (c *testClient) request(method string, params interface{}, result interface{}) *responseError {
+	c.nextID++
+	c.send(map[string]interface{}{"jsonrpc": "2.0", "id": c.nextID, "method": method, "params": params})
+
+	for {
+		message := c.receive()
+		if message.ID == nil {
+			c.notifications = append(c.notifications, message)
+			continue
+		}
+
+		require.Equal(c.t, c.nextID, *message.ID)
+		if message.Error != nil {
+			return message.Error
+		}
+		if result != nil {
+			require.NoError(c.t, json.Unmarshal(message.Result, result))
+		}
+		return nil
+	}
+}
+
+func (c *testClient) notify(method string, params interface{}) {
+	c.send(map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+// diagnostics returns the last published diagnostics of each uri since the last call.
+func (c *testClient) diagnostics() map[string][]Diagnostic {
+	// A request makes sure that all notifications sent before have been received.
+	c.request("shutdown", nil, nil)
+
+	result := make(map[string][]Diagnostic)
+	for _, n := range c.notifications {
+		if n.Method != "textDocument/publishDiagnostics" {
+			continue
+		}
+		var params PublishDiagnosticsParams
+		require.NoError(c.t, json.Unmarshal(n.Params, &params))
+		result[params.URI] = params.Diagnostics
+	}
+	c.notifications = nil
+	return result
+}
+
+func TestServer(t *testing.T) {
+	projectPath := t.TempDir()
+	files := map[string]string{
+		"main.go":                 "// @WHY intro\n// Hello\npackage main\n\n// @WHY group_a\n// A\n\n// @WHY intro\n// duplicate\n",
+		"templates/README.tpl.md": "# Readme\n{{ .Tag.intro }}\n{{ .Group \"group_\" }}\n{{ template \"footer\" . }}\n",
+		// The partials are templates as well, but files outside of the templates folder are not.
+		"templates/_partials/footer.tpl.md": "{{ index .Tag \"intro\" }}\n",
+		"docs/example.tpl.md":               "{{ .Tag.intro }}\n",
+	}
+	for name, content := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Join(projectPath, filepath.Dir(name)), 0775))
+		assert.NoError(t, os.WriteFile(filepath.Join(projectPath, name), []byte(content), 0664))
+	}
+
+	server := &Server{
+		ProjectPath:     projectPath,
+		TemplatesFolder: "templates",
+		New: func(overlay afero.Fs) (core.AtWhy, error) {
+			return core.New(generator.Markdown{}, projectPath, "/", "templates", nil, map[string]finder.CommentConfig{
+				".go": {LineComment: []string{"//"}},
+			}, core.Options{Overlay: overlay})
+		},
+		Rules: diagnostic.Rules{"duplicate-placeholder": diagnostic.SeverityError},
+	}
+
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Serve(serverReader, serverWriter)
+		serverWriter.Close()
+	}()
+
+	client := &testClient{
+		t:        t,
+		writer:   clientWriter,
+		messages: make(chan testMessage, 100),
+	}
+	go client.readMessages(clientReader)
+
+	mainURI := server.uri("main.go")
+	readmeURI := server.uri("templates/README.tpl.md")
+	footerURI := server.uri("templates/_partials/footer.tpl.md")
+	at := func(uri string, line, character int) TextDocumentPositionParams {
+		return TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: uri},
+			Position:     Position{Line: line, Character: character},
+		}
+	}
+
+	t.Run("initialize", func(t *testing.T) {
+		var result InitializeResult
+		assert.Nil(t, client.request("initialize", map[string]interface{}{}, &result))
+		assert.True(t, result.Capabilities.HoverProvider)
+		assert.Equal(t, textDocumentSyncKindFull, result.Capabilities.TextDocumentSync.Change)
+
+		client.notify("initialized", map[string]interface{}{})
+		assert.Equal(t, map[string][]Diagnostic{
+			mainURI: {{
+				Range:    Range{Start: Position{Line: 7}, End: Position{Line: 7, Character: 13}},
+				Severity: diagnosticSeverityError,
+				Code:     "duplicate-placeholder",
+				Source:   "atwhy",
+				Message:  "the placeholder intro is already used at main.go:1 (prefix it with + in all places to combine them)",
+			}},
+		}, client.diagnostics())
+	})
+
+	t.Run("definition", func(t *testing.T) {
+		var result []Location
+		assert.Nil(t, client.request("textDocument/definition", at(readmeURI, 1, 10), &result))
+		assert.Equal(t, []Location{
+			{URI: mainURI, Range: Range{Start: Position{Line: 0, Character: 3}, End: Position{Line: 1, Character: 8}}},
+			{URI: mainURI, Range: Range{Start: Position{Line: 7, Character: 3}, End: Position{Line: 8, Character: 12}}},
+		}, result)
+
+		assert.Nil(t, client.request("textDocument/definition", at(readmeURI, 0, 2), &result))
+		assert.Empty(t, result)
+	})
+
+	t.Run("references", func(t *testing.T) {
+		var result []Location
+		assert.Nil(t, client.request("textDocument/references", ReferenceParams{TextDocumentPositionParams: at(mainURI, 1, 0)}, &result))
+		assert.ElementsMatch(t, []Location{
+			{URI: readmeURI, Range: Range{Start: Position{Line: 1, Character: 8}, End: Position{Line: 1, Character: 13}}},
+			{URI: footerURI, Range: Range{Start: Position{Line: 0, Character: 15}, End: Position{Line: 0, Character: 20}}},
+		}, result)
+
+		// Groups are found by the tags starting with their prefix.
+		assert.Nil(t, client.request("textDocument/references", ReferenceParams{TextDocumentPositionParams: at(mainURI, 4, 5)}, &result))
+		assert.Equal(t, []Location{
+			{URI: readmeURI, Range: Range{Start: Position{Line: 2, Character: 11}, End: Position{Line: 2, Character: 17}}},
+		}, result)
+	})
+
+	t.Run("hover", func(t *testing.T) {
+		var result Hover
+		assert.Nil(t, client.request("textDocument/hover", at(readmeURI, 2, 13), &result))
+		assert.Equal(t, "Tags starting with `group_`:\n* `group_a` (main.go:5)", result.Contents.Value)
+		assert.Equal(t, &Range{Start: Position{Line: 2, Character: 11}, End: Position{Line: 2, Character: 17}}, result.Range)
+
+		assert.Nil(t, client.request("textDocument/hover", at(readmeURI, 1, 10), &result))
+		assert.Equal(t, "`intro` DOC main.go:1\n\nHello\n\n---\n\n`intro` DOC main.go:8\n\nduplicate", result.Contents.Value)
+
+		assert.Nil(t, client.request("textDocument/hover", at(footerURI, 0, 17), &result))
+		assert.Equal(t, &Range{Start: Position{Line: 0, Character: 15}, End: Position{Line: 0, Character: 20}}, result.Range)
+
+		// The file is no template, as it is outside of the templates folder.
+		var none *Hover
+		assert.Nil(t, client.request("textDocument/hover", at(server.uri("docs/example.tpl.md"), 0, 10), &none))
+		assert.Nil(t, none)
+	})
+
+	t.Run("completion with unsaved changes", func(t *testing.T) {
+		client.notify("textDocument/didOpen", DidOpenTextDocumentParams{
+			TextDocument: TextDocumentItem{URI: readmeURI, Version: 1, Text: "# Readme\n{{ .Tag.in"},
+		})
+
+		var result []CompletionItem
+		assert.Nil(t, client.request("textDocument/completion", at(readmeURI, 1, 10), &result))
+		assert.Equal(t, []CompletionItem{{
+			Label:         "intro",
+			Kind:          completionItemKindField,
+			Detail:        "DOC main.go:1",
+			Documentation: &MarkupContent{Kind: markupKindMarkdown, Value: "Hello"},
+		}}, result)
+
+		assert.Nil(t, client.request("textDocument/completion", at(server.uri("docs/example.tpl.md"), 0, 10), &result))
+		assert.Empty(t, result)
+
+		// The unsaved template is invalid and group_a is unused now.
+		got := client.diagnostics()
+		assert.Len(t, got[readmeURI], 1)
+		assert.Equal(t, "invalid-template", got[readmeURI][0].Code)
+	})
+
+	t.Run("closing the document restores the saved file", func(t *testing.T) {
+		client.notify("textDocument/didClose", DidCloseTextDocumentParams{TextDocument: TextDocumentIdentifier{URI: readmeURI}})
+
+		got := client.diagnostics()
+		assert.Empty(t, got[readmeURI])
+		assert.Len(t, got[mainURI], 1)
+	})
+
+	t.Run("unknown method", func(t *testing.T) {
+		err := client.request("textDocument/formatting", map[string]interface{}{}, nil)
+		assert.Equal(t, &responseError{Code: codeMethodNotFound, Message: "method not found: textDocument/formatting"}, err)
+	})
+
+	t.Run("exit", func(t *testing.T) {
+		client.notify("exit", nil)
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("the server did not exit")
+		}
+	})
+}
+
+func Test_position(t *testing.T) {
+	lines := []string{"// @WHY a", "// äöü 𝄞 x"}
+
+	tests := []struct {
+		name   string
+		line   int
+		column int
+		want   Position
+	}{
+		{name: "unknown line", want: Position{}},
+		{name: "unknown column", line: 2, want: Position{Line: 1}},
+		{name: "ascii", line: 1, column: 4, want: Position{Line: 0, Character: 3}},
+		// ä, ö and ü have 2 bytes but are 1 UTF-16 unit, 𝄞 has 4 bytes and 2 units.
+		{name: "unicode", line: 2, column: 16, want: Position{Line: 1, Character: 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := position(lines, tt.line, tt.column)
+			assert.Equal(t, tt.want, got)
+			if tt.column > 0 {
+				assert.Equal(t, tt.column-1, byteOffset(lines[tt.line-1], got.Character))
+			}
+		})
+	}
+}
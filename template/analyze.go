@@ -0,0 +1,466 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template/parse"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/core/tag"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/diagnostic"
+)
+
+var ErrMissingTag = errors.New("some templates reference tags which do not exist")
+
+// @WHY doc_template_usage3_missing_tags
+//
+// __Missing and unused tags:__
+// Referencing a tag which does not exist in the project fails the generation
+// (use `--missing=warning` to just report it).
+// Tags which are not used by any template are reported as warning.
+// Tags accessed dynamically (e.g. `{{ .Escape "{{ range .Tag }}" }}`) count as used.
+// Tags with a fallback (e.g. `{{ .Escape "{{ default \"TODO\" .Tag.example_tag }}" }}`) are never missing.
+
+// Reference is a usage of a tag inside of a template.
+type Reference struct {
+	// Placeholder is the name of the referenced tag.
+	// If Group is true, it is the prefix passed to .Group.
+	Placeholder string
+
+	// Group is true if all tags starting with the Placeholder are referenced.
+	// Dynamic accesses (e.g. {{ range .Tag }} or {{ .Tags.OfType "CODE" }}) are returned as a Group with
+	// an empty prefix, as they may use any tag.
+	Group bool
+
+	// Optional is true if the tag has a fallback (e.g. {{ default "fallback" .Tag.name }}).
+	// Optional tags can not be missing.
+	Optional bool
+
+	// File is the path of the template or partial containing the reference, relative to the project.
+	File string
+
+	// Template is the path of the template using the reference, relative to the project.
+	// It differs from the File for references inside of partials and is empty
+	// if the template does not use the partial.
+	Template string
+
+	// Line and Column are 1-based.
+	Line   int
+	Column int
+}
+
+// Matches checks if the given placeholder is referenced.
+func (r Reference) Matches(placeholder string) bool {
+	if r.Group {
+		return strings.HasPrefix(placeholder, r.Placeholder)
+	}
+	return r.Placeholder == placeholder
+}
+
+// Analysis is the result of Analyze.
+type Analysis struct {
+	// Missing contains all references to tags which do not exist.
+	Missing []Reference
+
+	// Unused contains all tags which are not referenced by any template.
+	Unused []tag.Tag
+}
+
+// References returns all tag references of the template.
+//
+// Only the tags which are accessed through the template data are found,
+// e.g. {{ .Tag.name }}, {{ $.Tag.name }}, {{ index .Tag "name" }}, {{ .Group "prefix" }} and {{ .Tags.WithPrefix "prefix" }}.
+func (t Markdown) References() []Reference {
+	if t.template == nil {
+		return nil
+	}
+
+	a := referenceAnalyzer{}
+	// The references and the called templates of each tree.
+	type treeResult struct {
+		start, end int
+		calls      []string
+	}
+	results := make(map[*parse.Tree]treeResult)
+	for _, tpl := range t.template.Templates() {
+		// The body of a template using a layout is added a second time as its content block.
+		if tpl.Tree == nil || tpl.Tree.Root == nil {
+			continue
+		}
+		if _, ok := results[tpl.Tree]; ok {
+			continue
+		}
+
+		// The trees of the partials are reported in their own files.
+		a.file, a.lineOffset = t.file, t.lineOffset
+		if file, ok := t.partialFiles[tpl.Tree.ParseName]; ok {
+			a.file, a.lineOffset = file, 0
+		}
+
+		start := len(a.references)
+		a.tree, a.calls = tpl.Tree, nil
+		a.walk(tpl.Tree.Root)
+		results[tpl.Tree] = treeResult{start: start, end: len(a.references), calls: a.calls}
+	}
+
+	// Only the trees reachable from the executed template are used by it.
+	queue := []*parse.Tree{t.template.Tree}
+	used := make(map[*parse.Tree]bool)
+	for len(queue) > 0 {
+		tree := queue[0]
+		queue = queue[1:]
+		if tree == nil || used[tree] {
+			continue
+		}
+		used[tree] = true
+
+		for _, name := range results[tree].calls {
+			if called := t.template.Lookup(name); called != nil {
+				queue = append(queue, called.Tree)
+			}
+		}
+	}
+
+	for tree, result := range results {
+		if !used[tree] {
+			continue
+		}
+		for i := result.start; i < result.end; i++ {
+			a.references[i].Template = t.file
+		}
+	}
+
+	return a.references
+}
+
+// ReferencedTags returns the tags referenced by the template (including its partials),
+// sorted by their placeholder.
+func (t Markdown) ReferencedTags() []tag.Tag {
+	references := t.References()
+
+	var result []tag.Tag
+	for placeholder, current := range t.tagMap {
+		for _, r := range references {
+			if r.Matches(placeholder) {
+				result = append(result, current)
+				break
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Placeholder() < result[j].Placeholder()
+	})
+	return result
+}
+
+// References returns the tag references of all templates.
+// References inside of the partials are only returned once, even if they are shared by several templates.
+// Their Template is the first template using them.
+func References(templates []Markdown) []Reference {
+	var references []Reference
+	seen := make(map[Reference]int)
+	for _, t := range templates {
+		for _, r := range t.References() {
+			location := r
+			location.Template = ""
+			if i, ok := seen[location]; ok {
+				if references[i].Template == "" {
+					references[i].Template = r.Template
+				}
+				continue
+			}
+			seen[location] = len(references)
+			references = append(references, r)
+		}
+	}
+	return references
+}
+
+// UsedReferences returns the references used by each of the templates.
+// References inside of the partials are returned once for each template using them
+// and references inside of unused partials are skipped.
+func UsedReferences(templates []Markdown) []Reference {
+	var references []Reference
+	for _, t := range templates {
+		for _, r := range t.References() {
+			if r.Template != "" {
+				references = append(references, r)
+			}
+		}
+	}
+	return references
+}
+
+// Analyze checks the references of all templates against the tags.
+func Analyze(templates []Markdown, tags []tag.Tag) Analysis {
+	references := References(templates)
+
+	result := Analysis{}
+	for _, r := range references {
+		// Dynamic accesses can not be missing.
+		if r.Optional || r.Group && r.Placeholder == "" {
+			continue
+		}
+
+		found := false
+		for _, t := range tags {
+			if r.Matches(t.Placeholder()) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result.Missing = append(result.Missing, r)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, t := range tags {
+		if seen[t.Placeholder()] {
+			continue
+		}
+		seen[t.Placeholder()] = true
+
+		used := false
+		for _, r := range references {
+			if r.Matches(t.Placeholder()) {
+				used = true
+				break
+			}
+		}
+		if !used {
+			result.Unused = append(result.Unused, t)
+		}
+	}
+
+	return result
+}
+
+// analyze reports the result of Analyze.
+// It only returns an error if missing tags are reported as errors.
+func (l Loader) analyze(templates []Markdown, tags mappedTags) error {
+	tagList := make([]tag.Tag, 0, len(tags))
+	for _, t := range tags {
+		tagList = append(tagList, t)
+	}
+	sort.Slice(tagList, func(i, j int) bool {
+		if tagList[i].File() != tagList[j].File() {
+			return tagList[i].File() < tagList[j].File()
+		}
+		return tagList[i].Line() < tagList[j].Line()
+	})
+
+	severity := l.MissingSeverity
+	if severity == "" {
+		severity = diagnostic.SeverityError
+	}
+
+	analysis := Analyze(templates, tagList)
+
+	for _, r := range analysis.Missing {
+		message := "the tag " + r.Placeholder + " does not exist"
+		if r.Group {
+			message = "there is no tag starting with " + r.Placeholder
+		}
+		diagnostic.Report(l.Reporter, diagnostic.Diagnostic{
+			Severity: severity,
+			Code:     "missing-tag",
+			File:     r.File,
+			Line:     r.Line,
+			Column:   r.Column,
+			Message:  message,
+		})
+	}
+
+	for _, t := range analysis.Unused {
+		diagnostic.Report(l.Reporter, diagnostic.Diagnostic{
+			Severity: diagnostic.SeverityWarning,
+			Code:     "unused-tag",
+			File:     t.File(),
+			Line:     t.Line(),
+			Message:  "the tag " + t.Placeholder() + " is not used by any template",
+		})
+	}
+
+	if len(analysis.Missing) > 0 && severity == diagnostic.SeverityError {
+		return fmt.Errorf("%w (%v found)", ErrMissingTag, len(analysis.Missing))
+	}
+	return nil
+}
+
+type referenceAnalyzer struct {
+	file       string
+	lineOffset int
+	tree       *parse.Tree
+
+	// calls contains the names of the templates called in the tree.
+	calls []string
+
+	// optional is true while walking the arguments of default.
+	optional bool
+
+	references []Reference
+}
+
+func (a *referenceAnalyzer) add(node parse.Node, placeholder string, group bool) {
+	r := Reference{
+		Placeholder: placeholder,
+		Group:       group,
+		Optional:    a.optional,
+		File:        a.file,
+	}
+
+	// The location has the format "name:line:byteOffset".
+	location, _ := a.tree.ErrorContext(node)
+	parts := strings.Split(location, ":")
+	if len(parts) >= 3 {
+		r.Line, _ = strconv.Atoi(parts[len(parts)-2])
+		r.Line += a.lineOffset
+		r.Column, _ = strconv.Atoi(parts[len(parts)-1])
+		r.Column++
+	}
+
+	a.references = append(a.references, r)
+}
+
+func (a *referenceAnalyzer) walk(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			a.walk(child)
+		}
+	case *parse.ActionNode:
+		a.walk(n.Pipe)
+	case *parse.IfNode:
+		a.walkBranch(&n.BranchNode)
+	case *parse.RangeNode:
+		a.walkBranch(&n.BranchNode)
+	case *parse.WithNode:
+		a.walkBranch(&n.BranchNode)
+	case *parse.TemplateNode:
+		a.calls = append(a.calls, n.Name)
+		a.walk(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		// The commands before default are optional, e.g. {{ .Tag.name | default "fallback" }}.
+		lastDefault := -1
+		for i, cmd := range n.Cmds {
+			if isDefault(cmd) {
+				lastDefault = i
+			}
+		}
+		for i, cmd := range n.Cmds {
+			if i < lastDefault {
+				a.walkOptional(cmd)
+				continue
+			}
+			a.walk(cmd)
+		}
+	case *parse.CommandNode:
+		a.walkCommand(n)
+	case *parse.ChainNode:
+		a.walk(n.Node)
+	case *parse.FieldNode:
+		a.walkFields(n, n.Ident)
+	case *parse.VariableNode:
+		if len(n.Ident) > 0 && n.Ident[0] == "$" {
+			a.walkFields(n, n.Ident[1:])
+		}
+	}
+}
+
+func (a *referenceAnalyzer) walkBranch(n *parse.BranchNode) {
+	a.walk(n.Pipe)
+	a.walk(n.List)
+	a.walk(n.ElseList)
+}
+
+func (a *referenceAnalyzer) walkOptional(node parse.Node) {
+	optional := a.optional
+	a.optional = true
+	a.walk(node)
+	a.optional = optional
+}
+
+func (a *referenceAnalyzer) walkCommand(n *parse.CommandNode) {
+	// {{ default "fallback" .Tag.name }}
+	if isDefault(n) {
+		for _, arg := range n.Args[1:] {
+			a.walkOptional(arg)
+		}
+		return
+	}
+
+	if len(n.Args) >= 2 {
+		// {{ .Group "prefix" }} and {{ .Tags.WithPrefix "prefix" }}
+		fields := dataFields(n.Args[0])
+		isGroup := len(fields) == 1 && fields[0] == "Group"
+		isTagsWithPrefix := len(fields) == 2 && fields[0] == "Tags" && fields[1] == "WithPrefix"
+		if isGroup || isTagsWithPrefix {
+			if prefix, ok := n.Args[1].(*parse.StringNode); ok {
+				a.add(prefix, prefix.Text, true)
+				return
+			}
+		}
+	}
+
+	if len(n.Args) == 3 {
+		// {{ index .Tag "name" }}
+		if ident, ok := n.Args[0].(*parse.IdentifierNode); ok && ident.Ident == "index" {
+			fields := dataFields(n.Args[1])
+			name, isString := n.Args[2].(*parse.StringNode)
+			if isString && len(fields) == 1 && fields[0] == "Tag" {
+				a.add(name, name.Text, false)
+				return
+			}
+		}
+	}
+
+	for _, arg := range n.Args {
+		a.walk(arg)
+	}
+}
+
+func (a *referenceAnalyzer) walkFields(node parse.Node, fields []string) {
+	if len(fields) == 0 || fields[0] != "Tag" && fields[0] != "Tags" {
+		return
+	}
+
+	// All other queries of .Tags may use any tag.
+	if len(fields) == 1 || fields[0] == "Tags" {
+		a.add(node, "", true)
+		return
+	}
+	a.add(node, fields[1], false)
+}
+
+// isDefault checks if the command calls the default function.
+func isDefault(n *parse.CommandNode) bool {
+	if len(n.Args) == 0 {
+		return false
+	}
+	ident, ok := n.Args[0].(*parse.IdentifierNode)
+	return ok && ident.Ident == "default"
+}
+
+// dataFields returns the fields of a node accessing the template data
+// (e.g. [Tag] for .Tag and $.Tag). It returns nil for all other nodes.
+func dataFields(node parse.Node) []string {
+	switch n := node.(type) {
+	case *parse.FieldNode:
+		return n.Ident
+	case *parse.VariableNode:
+		if len(n.Ident) > 0 && n.Ident[0] == "$" {
+			return n.Ident[1:]
+		}
+	}
+	return nil
+}
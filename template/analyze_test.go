@@ -0,0 +1,137 @@
+package template
+
+import (
+	"testing"
+	"text/template"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/core/tag"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/diagnostic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkdown_References(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		want     []Reference
+	}{
+		{
+			name:     "no references",
+			template: "# Title {{ .Meta.Title }}",
+			want:     nil,
+		},
+		{
+			name:     "tag",
+			template: "# Title\n\n{{ .Tag.some_tag }}",
+			want: []Reference{
+				{Placeholder: "some_tag", File: "README.tpl.md", Template: "README.tpl.md", Line: 5, Column: 8},
+			},
+		},
+		{
+			name:     "nested in other actions",
+			template: `{{ if .Tag.a }}{{ $.Tag.b }}{{ else }}{{ index .Tag "c" }}{{ end }}{{ .Group "d_" | printf "%s" }}`,
+			want: []Reference{
+				{Placeholder: "a", File: "README.tpl.md", Template: "README.tpl.md", Line: 3, Column: 11},
+				{Placeholder: "b", File: "README.tpl.md", Template: "README.tpl.md", Line: 3, Column: 20},
+				{Placeholder: "c", File: "README.tpl.md", Template: "README.tpl.md", Line: 3, Column: 53},
+				{Placeholder: "d_", Group: true, File: "README.tpl.md", Template: "README.tpl.md", Line: 3, Column: 78},
+			},
+		},
+		{
+			name:     "dynamic access",
+			template: "{{ range .Tag }}{{ . }}{{ end }}",
+			want: []Reference{
+				{Placeholder: "", Group: true, File: "README.tpl.md", Template: "README.tpl.md", Line: 3, Column: 10},
+			},
+		},
+		{
+			name:     "tags query",
+			template: `{{ range .Tags.WithPrefix "a_" }}{{ end }}{{ .Tags.OfType "CODE" }}`,
+			want: []Reference{
+				{Placeholder: "a_", Group: true, File: "README.tpl.md", Template: "README.tpl.md", Line: 3, Column: 27},
+				{Placeholder: "", Group: true, File: "README.tpl.md", Template: "README.tpl.md", Line: 3, Column: 51},
+			},
+		},
+		{
+			name:     "optional tags",
+			template: `{{ default "x" .Tag.a }}{{ .Tag.b | default "x" | upper }}{{ .Tag.c | upper }}`,
+			want: []Reference{
+				{Placeholder: "a", Optional: true, File: "README.tpl.md", Template: "README.tpl.md", Line: 3, Column: 20},
+				{Placeholder: "b", Optional: true, File: "README.tpl.md", Template: "README.tpl.md", Line: 3, Column: 32},
+				{Placeholder: "c", File: "README.tpl.md", Template: "README.tpl.md", Line: 3, Column: 66},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md := Markdown{
+				template:   template.Must(template.New("README.tpl.md").Funcs(funcs).Parse(tt.template)),
+				file:       "README.tpl.md",
+				lineOffset: 2,
+			}
+			assert.Equal(t, tt.want, md.References())
+		})
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	templates := []Markdown{
+		{template: template.Must(template.New("a").Parse(`{{ .Tag.used }} {{ .Tag.missing }}`)), file: "a.tpl.md"},
+		{template: template.Must(template.New("b").Parse(`{{ .Group "group_" }} {{ .Group "empty_" }}`)), file: "b.tpl.md"},
+	}
+	used := fakeTag{name: "used"}
+	group1 := fakeTag{name: "group_1"}
+	group2 := fakeTag{name: "group_2"}
+	unused := fakeTag{name: "unused"}
+
+	got := Analyze(templates, []tag.Tag{used, group1, unused, group2})
+	assert.Equal(t, Analysis{
+		Missing: []Reference{
+			{Placeholder: "missing", File: "a.tpl.md", Template: "a.tpl.md", Line: 1, Column: 24},
+			{Placeholder: "empty_", Group: true, File: "b.tpl.md", Template: "b.tpl.md", Line: 1, Column: 33},
+		},
+		Unused: []tag.Tag{unused},
+	}, got)
+
+	t.Run("dynamic access uses all tags", func(t *testing.T) {
+		templates := []Markdown{
+			{template: template.Must(template.New("a").Parse(`{{ range $name, $tag := .Tag }}{{ $name }}{{ end }}`))},
+		}
+		assert.Equal(t, Analysis{}, Analyze(templates, []tag.Tag{used, unused}))
+	})
+
+	t.Run("optional tags are used but never missing", func(t *testing.T) {
+		templates := []Markdown{
+			{template: template.Must(template.New("a").Funcs(funcs).Parse(`{{ default "x" .Tag.used }} {{ default "x" .Tag.missing }}`))},
+		}
+		assert.Equal(t, Analysis{Unused: []tag.Tag{unused}}, Analyze(templates, []tag.Tag{used, unused}))
+	})
+}
+
+func TestLoader_Load_analysis(t *testing.T) {
+	fs := testFileFS("README.tpl.md", []byte("# Readme\n{{ .Tag.used }}\n{{ .Tag.missing }}\n"))
+	tags := []tag.Tag{fakeTag{name: "used"}, fakeTag{name: "unused"}}
+
+	t.Run("missing tags are errors by default", func(t *testing.T) {
+		diagnostics := &diagnostic.Collector{}
+		_, err := Loader{FS: fs, Folder: "templates", Reporter: diagnostics}.Load(tags)
+		assert.ErrorIs(t, err, ErrMissingTag)
+
+		var got []string
+		for _, d := range diagnostics.Diagnostics() {
+			got = append(got, d.String())
+		}
+		assert.Equal(t, []string{
+			": warning: the tag unused is not used by any template",
+			"templates/README.tpl.md:3:8: error: the tag missing does not exist",
+		}, got)
+	})
+
+	t.Run("missing tags can be warnings", func(t *testing.T) {
+		diagnostics := &diagnostic.Collector{}
+		got, err := Loader{FS: fs, Reporter: diagnostics, MissingSeverity: diagnostic.SeverityWarning}.Load(tags)
+		assert.NoError(t, err)
+		assert.Len(t, got, 1)
+		assert.Equal(t, 2, diagnostics.Count(diagnostic.SeverityWarning))
+	})
+}
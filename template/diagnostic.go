@@ -0,0 +1,81 @@
+package template
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/diagnostic"
+	"gopkg.in/yaml.v2"
+)
+
+// templateErrorRegex matches the location in errors of the Go template engine, e.g.
+//
+//	template: README.tpl.md:12: function "foo" not defined
+//	template: README.tpl.md:12:5: executing "README.tpl.md" at <.foo>: ...
+var templateErrorRegex = regexp.MustCompile(`(?s)^template: .*?:(\d+):(?:(\d+):)? (.*)$`)
+
+// yamlLineRegex matches the line in errors of the yaml parser, e.g.
+//
+//	line 3: field titel not found in type template.MetaData
+var yamlLineRegex = regexp.MustCompile(`line (\d+): (.*)`)
+
+var yamlUnknownFieldRegex = regexp.MustCompile(`^field (\S+) not found in type`)
+
+// templateDiagnostic converts an error of the template engine to a Diagnostic.
+// lineOffset is the number of lines before the template body.
+func templateDiagnostic(file string, lineOffset int, err error) diagnostic.Diagnostic {
+	d := diagnostic.Diagnostic{
+		Severity: diagnostic.SeverityError,
+		Code:     "invalid-template",
+		File:     file,
+		Message:  err.Error(),
+		Err:      err,
+	}
+
+	if match := templateErrorRegex.FindStringSubmatch(err.Error()); match != nil {
+		d.Line, _ = strconv.Atoi(match[1])
+		d.Line += lineOffset
+		d.Column, _ = strconv.Atoi(match[2])
+		d.Message = match[3]
+	}
+
+	return d
+}
+
+// headerDiagnostics converts an error of the yaml parser to Diagnostics.
+// The header always starts in the second line of the file.
+func headerDiagnostics(file string, severity diagnostic.Severity, err error) []diagnostic.Diagnostic {
+	messages := []string{err.Error()}
+
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	}
+
+	var result []diagnostic.Diagnostic
+	for _, message := range messages {
+		d := diagnostic.Diagnostic{
+			Severity: severity,
+			Code:     "invalid-header",
+			File:     file,
+			Message:  message,
+			Err:      err,
+		}
+
+		if match := yamlLineRegex.FindStringSubmatch(message); match != nil {
+			d.Line, _ = strconv.Atoi(match[1])
+			d.Line++
+			d.Message = "invalid template header: " + match[2]
+
+			if unknown := yamlUnknownFieldRegex.FindStringSubmatch(match[2]); unknown != nil {
+				d.Code = "unknown-header-key"
+				d.Message = "unknown key " + strconv.Quote(unknown[1]) + " in the template header"
+			}
+		}
+
+		result = append(result, d)
+	}
+
+	return result
+}
@@ -0,0 +1,276 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+var ErrNoList = errors.New("the value is no list")
+
+// @WHY doc_template_usage5_functions
+//
+// __Template functions:__
+// The values are always passed as last argument, so that they can be used in pipelines,
+// e.g. `{{"{{ .Tag.example_tag | upper }}"}}` or `{{"{{ upper .Tag.example_tag }}"}}`.
+// Tags can be passed to all functions expecting text.
+// * Text: `upper`, `lower`, `title`, `trim`,
+//   `{{"{{ replace \"old\" \"new\" .Tag.example_tag }}"}}`,
+//   `{{"{{ indent 4 .Tag.example_tag }}"}}` (indents all lines which are not empty) and
+//   `{{"{{ wrap 80 .Tag.example_tag }}"}}` (wraps the lines after the given number of characters).
+// * Lists: `{{"{{ list \"b\" \"a\" }}"}}` and `{{"{{ split \",\" \"b,a\" }}"}}` create lists,
+//   `{{"{{ join \", \" $list }}"}}`, `{{"{{ sort $list }}"}}` (alphanumeric, like `.Group`), `{{"{{ first $list }}"}}`
+//   and `{{"{{ filter \"^a\" $list }}"}}` (keeps all values matching the regular expression) use them.
+// * Dates: `{{"{{ .Now }}"}}` uses the format `02 Jan 06 15:04 -0700`,
+//   other formats can be passed in the [Go format](https://pkg.go.dev/time#pkg-constants): `{{"{{ .Now \"2006-01-02\" }}"}}`.
+// * Markdown: `{{"{{ shiftHeadings 1 .Tag.example_tag }}"}}` adds one level to all headings (use negative numbers to remove levels),
+//   `{{"{{ code \"go\" .Tag.example_tag }}"}}` wraps the text in a code block
+//   and `{{"{{ table (list \"Name\" \"Value\") (list (list \"a\" \"1\") (list \"b\" \"2\")) }}"}}` creates a table from the header and the rows.
+// * Missing tags: `{{"{{ default \"fallback\" .Tag.example_tag }}"}}` uses the fallback if the tag does not exist or is empty.
+//   Such optional tags are not reported as missing.
+
+// funcs are the functions available in all templates.
+var funcs = template.FuncMap{
+	"upper":         func(value interface{}) string { return strings.ToUpper(toString(value)) },
+	"lower":         func(value interface{}) string { return strings.ToLower(toString(value)) },
+	"title":         func(value interface{}) string { return cases.Title(language.English).String(toString(value)) },
+	"trim":          func(value interface{}) string { return strings.TrimSpace(toString(value)) },
+	"replace":       replace,
+	"indent":        indent,
+	"wrap":          wrap,
+	"list":          func(values ...interface{}) []interface{} { return values },
+	"split":         func(sep string, value interface{}) []string { return strings.Split(toString(value), sep) },
+	"join":          join,
+	"sort":          sortList,
+	"first":         first,
+	"filter":        filter,
+	"shiftHeadings": shiftHeadings,
+	"code":          code,
+	"table":         table,
+	"default":       defaultValue,
+}
+
+// toString converts any value of a template to its text.
+// nil results in an empty string.
+func toString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprint(value)
+}
+
+// toList converts any slice or array to a list.
+// nil results in an empty list.
+func toList(value interface{}) ([]interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("%w: %T", ErrNoList, value)
+	}
+
+	result := make([]interface{}, v.Len())
+	for i := range result {
+		result[i] = v.Index(i).Interface()
+	}
+	return result, nil
+}
+
+func replace(old, new string, value interface{}) string {
+	return strings.ReplaceAll(toString(value), old, new)
+}
+
+func indent(spaces int, value interface{}) string {
+	lines := strings.Split(toString(value), "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			lines[i] = strings.Repeat(" ", spaces) + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// wrap breaks each line at the last space before the width.
+// Words longer than the width are not split.
+func wrap(width int, value interface{}) string {
+	lines := strings.Split(toString(value), "\n")
+	for i, line := range lines {
+		var wrapped []string
+		current := ""
+		for _, word := range strings.Fields(line) {
+			if current != "" && len([]rune(current))+1+len([]rune(word)) > width {
+				wrapped = append(wrapped, current)
+				current = ""
+			}
+			if current != "" {
+				current += " "
+			}
+			current += word
+		}
+		if current != "" || len(wrapped) == 0 {
+			wrapped = append(wrapped, current)
+		}
+		lines[i] = strings.Join(wrapped, "\n")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func join(sep string, value interface{}) (string, error) {
+	list, err := toList(value)
+	if err != nil {
+		return "", err
+	}
+
+	texts := make([]string, len(list))
+	for i, v := range list {
+		texts[i] = toString(v)
+	}
+	return strings.Join(texts, sep), nil
+}
+
+// sortList sorts the values alphanumerically by their text.
+func sortList(value interface{}) ([]interface{}, error) {
+	list, err := toList(value)
+	if err != nil {
+		return nil, err
+	}
+
+	col := collate.New(language.Make("en-US"), collate.Numeric)
+	sort.SliceStable(list, func(i, j int) bool {
+		return col.CompareString(toString(list[i]), toString(list[j])) == -1
+	})
+	return list, nil
+}
+
+// first returns the first value of the list or nil if it is empty.
+func first(value interface{}) (interface{}, error) {
+	list, err := toList(value)
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+	return list[0], nil
+}
+
+// filter keeps all values whose text matches the regular expression.
+func filter(pattern string, value interface{}) ([]interface{}, error) {
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := toList(value)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []interface{}{}
+	for _, v := range list {
+		if regex.MatchString(toString(v)) {
+			result = append(result, v)
+		}
+	}
+	return result, nil
+}
+
+var headingRegex = regexp.MustCompile(`^(#{1,6})(\s|$)`)
+
+// shiftHeadings changes the level of all headings by the given number of levels.
+// The levels are kept between 1 and 6 and code blocks are not changed.
+func shiftHeadings(levels int, value interface{}) string {
+	lines := strings.Split(toString(value), "\n")
+	inCode := false
+	for i, line := range lines {
+		if strings.HasPrefix(line, "```") {
+			inCode = !inCode
+		}
+		match := headingRegex.FindStringSubmatch(line)
+		if inCode || match == nil {
+			continue
+		}
+
+		level := len(match[1]) + levels
+		if level < 1 {
+			level = 1
+		} else if level > 6 {
+			level = 6
+		}
+		lines[i] = strings.Repeat("#", level) + line[len(match[1]):]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// code wraps the text in a fenced code block.
+// The fence is longer than any backtick fence inside of the text.
+func code(language string, value interface{}) string {
+	text := strings.TrimSuffix(toString(value), "\n")
+
+	fence := "```"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	return fence + language + "\n" + text + "\n" + fence
+}
+
+// table creates a markdown table.
+// Each row is a list of cells.
+func table(header interface{}, rows interface{}) (string, error) {
+	headerList, err := toList(header)
+	if err != nil {
+		return "", err
+	}
+	rowList, err := toList(rows)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	writeRow := func(cells []interface{}) {
+		b.WriteString("|")
+		for i := range headerList {
+			cell := ""
+			if i < len(cells) {
+				cell = toString(cells[i])
+			}
+			// Cells can neither contain new lines nor unescaped pipes.
+			cell = strings.ReplaceAll(strings.TrimSpace(cell), "|", `\|`)
+			cell = strings.ReplaceAll(cell, "\n", "<br>")
+			b.WriteString(" " + cell + " |")
+		}
+		b.WriteString("\n")
+	}
+
+	writeRow(headerList)
+	b.WriteString("|" + strings.Repeat(" --- |", len(headerList)) + "\n")
+	for _, row := range rowList {
+		cells, err := toList(row)
+		if err != nil {
+			return "", err
+		}
+		writeRow(cells)
+	}
+
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+// defaultValue returns the fallback if the value is nil or its text is empty.
+func defaultValue(fallback interface{}, value interface{}) interface{} {
+	if strings.TrimSpace(toString(value)) == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,76 @@
+package template
+
+import (
+	"bytes"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/core/tag"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_funcs(t *testing.T) {
+	tags := map[string]tag.Tag{
+		"text":    fakeTag{name: "text", value: "Hello World"},
+		"heading": fakeTag{name: "heading", value: "# A\n```\n# no heading\n```\n###### B\n#no heading"},
+		"empty":   fakeTag{name: "empty", value: " \n"},
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantErr  assert.ErrorAssertionFunc
+	}{
+		{name: "upper", template: `{{ .Tag.text | upper }}`, want: "HELLO WORLD", wantErr: assert.NoError},
+		{name: "lower", template: `{{ lower .Tag.text }}`, want: "hello world", wantErr: assert.NoError},
+		{name: "title", template: `{{ title "hello wORLD" }}`, want: "Hello World", wantErr: assert.NoError},
+		{name: "trim", template: `[{{ trim "  a b \n" }}]`, want: "[a b]", wantErr: assert.NoError},
+		{name: "replace", template: `{{ replace "World" "atwhy" .Tag.text }}`, want: "Hello atwhy", wantErr: assert.NoError},
+		{name: "indent skips empty lines", template: `{{ indent 2 "a\n\nb" }}`, want: "  a\n\n  b", wantErr: assert.NoError},
+		{name: "wrap", template: `{{ wrap 10 "aaa bbb ccc dddddddddddd e\n\nf" }}`, want: "aaa bbb\nccc\ndddddddddddd\ne\n\nf", wantErr: assert.NoError},
+		{name: "join", template: `{{ list "a" 1 .Tag.text | join ", " }}`, want: "a, 1, Hello World", wantErr: assert.NoError},
+		{name: "sort is alphanumeric", template: `{{ split "," "b10,a,b2" | sort | join " " }}`, want: "a b2 b10", wantErr: assert.NoError},
+		{name: "first", template: `{{ first (list "a" "b") }} {{ first (list) }}`, want: "a <no value>", wantErr: assert.NoError},
+		{name: "filter", template: `{{ list "ab" "b" "ac" | filter "^a" | join " " }}`, want: "ab ac", wantErr: assert.NoError},
+		{name: "filter with invalid regex", template: `{{ filter "(" (list) }}`, wantErr: assert.Error},
+		{name: "no list", template: `{{ join "," "a" }}`, wantErr: assert.Error},
+		{
+			name:     "shiftHeadings",
+			template: `{{ shiftHeadings 1 .Tag.heading }}`,
+			want:     "## A\n```\n# no heading\n```\n###### B\n#no heading",
+			wantErr:  assert.NoError,
+		},
+		{name: "shiftHeadings keeps level 1", template: `{{ shiftHeadings -2 "## A" }}`, want: "# A", wantErr: assert.NoError},
+		{name: "code", template: `{{ code "go" "package main\n" }}`, want: "```go\npackage main\n```", wantErr: assert.NoError},
+		{name: "code with fences", template: "{{ code \"md\" \"```\" }}", want: "````md\n```\n````", wantErr: assert.NoError},
+		{
+			name:     "table",
+			template: `{{ table (list "Name" "Value") (list (list "a" "x|y") (list "b\nc")) }}`,
+			want:     "| Name | Value |\n| --- | --- |\n| a | x\\|y |\n| b<br>c |  |",
+			wantErr:  assert.NoError,
+		},
+		{name: "default of a missing tag", template: `{{ default "TODO" .Tag.missing }}`, want: "TODO", wantErr: assert.NoError},
+		{name: "default of an empty tag", template: `{{ .Tag.empty | default "TODO" }}`, want: "TODO", wantErr: assert.NoError},
+		{name: "default of an existing tag", template: `{{ .Tag.text | default "TODO" }}`, want: "Hello World", wantErr: assert.NoError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpl := template.Must(template.New("test").Funcs(funcs).Parse(tt.template))
+
+			var buf bytes.Buffer
+			err := tpl.Execute(&buf, data{Tag: tags})
+			if !tt.wantErr(t, err) || err != nil {
+				return
+			}
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func Test_data_Now(t *testing.T) {
+	d := data{now: time.Date(2022, 3, 4, 5, 6, 0, 0, time.UTC)}
+	assert.Equal(t, "04 Mar 22 05:06 +0000", d.Now())
+	assert.Equal(t, "2022-03-04", d.Now("2006-01-02"))
+}
@@ -0,0 +1,106 @@
+package template
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/core/tag"
+)
+
+// The values of the tags are wrapped with markers to find them in the executed template.
+// They use characters of the private use area, so that they are not changed by the template functions.
+const (
+	tagStartMarker = "\uE000"
+	tagEndMarker   = "\uE001"
+	tagMarkerClose = "\uE002"
+)
+
+var tagMarkerRegex = regexp.MustCompile(tagStartMarker + `(\d+)` + tagMarkerClose + `|` + tagEndMarker + `(\d+)` + tagMarkerClose)
+
+// markedTag wraps the value of the tag with the markers of its index.
+type markedTag struct {
+	tag.Tag
+	index int
+}
+
+func (m markedTag) String() string {
+	value := m.Tag.String()
+	// Empty tags stay empty, e.g. for {{ default }}.
+	if strings.TrimSpace(value) == "" {
+		return value
+	}
+
+	index := strconv.Itoa(m.index)
+	return tagStartMarker + index + tagMarkerClose + value + tagEndMarker + index + tagMarkerClose
+}
+
+// TagLines executes the template and returns for each line of the result the referenced tags
+// whose value is (partly) in that line.
+// It returns nil if the tags can not be located, e.g. because a template function changed the order of the values.
+func (t Markdown) TagLines() ([][]tag.Tag, error) {
+	referenced := t.ReferencedTags()
+	if len(referenced) == 0 {
+		return nil, nil
+	}
+
+	// Both executions have to use the same time.
+	if t.Now.IsZero() {
+		t.Now = time.Now()
+	}
+
+	plain := bytes.Buffer{}
+	if err := t.Execute(&plain); err != nil {
+		return nil, err
+	}
+
+	marked := t
+	marked.tagMap = make(map[string]tag.Tag, len(t.tagMap))
+	for placeholder, current := range t.tagMap {
+		marked.tagMap[placeholder] = current
+	}
+	for i, current := range referenced {
+		marked.tagMap[current.Placeholder()] = markedTag{Tag: current, index: i}
+	}
+
+	result := bytes.Buffer{}
+	if err := marked.Execute(&result); err != nil {
+		return nil, err
+	}
+
+	var lines [][]tag.Tag
+	var text strings.Builder
+	open := make(map[int]bool)
+	for _, line := range strings.SplitAfter(result.String(), "\n") {
+		inLine := make(map[int]bool)
+		for index := range open {
+			inLine[index] = true
+		}
+
+		for _, match := range tagMarkerRegex.FindAllStringSubmatch(line, -1) {
+			if match[1] != "" {
+				index, _ := strconv.Atoi(match[1])
+				open[index], inLine[index] = true, true
+			} else {
+				index, _ := strconv.Atoi(match[2])
+				delete(open, index)
+			}
+		}
+
+		var tags []tag.Tag
+		for i, current := range referenced {
+			if inLine[i] {
+				tags = append(tags, current)
+			}
+		}
+		lines = append(lines, tags)
+		text.WriteString(tagMarkerRegex.ReplaceAllString(line, ""))
+	}
+
+	if text.String() != plain.String() {
+		return nil, nil
+	}
+	return lines, nil
+}
@@ -0,0 +1,70 @@
+package template
+
+import (
+	"testing"
+	"text/template"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/core/tag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkdown_TagLines(t *testing.T) {
+	tags := map[string]tag.Tag{
+		"a":     fakeTag{name: "a", value: "x\ny"},
+		"b":     fakeTag{name: "b", value: "w"},
+		"empty": fakeTag{name: "empty", value: ""},
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     [][]string
+	}{
+		{
+			name:     "no references",
+			template: "# Title",
+			want:     nil,
+		},
+		{
+			name:     "tags in several lines",
+			template: "# Title\n{{ .Tag.a }} {{ .Tag.b }}\nend",
+			want:     [][]string{nil, {"a"}, {"a", "b"}, nil},
+		},
+		{
+			name:     "tags in functions",
+			template: "{{ .Tag.b | upper }}\n{{ .Tag.empty | default \"TODO\" }}",
+			want:     [][]string{{"b"}, nil},
+		},
+		{
+			name:     "sorted tags",
+			template: `{{ list .Tag.a .Tag.b | sort | join "," }}`,
+			want:     [][]string{{"a", "b"}, {"a"}},
+		},
+		{
+			name:     "wrapped tags can not be located",
+			template: `{{ list .Tag.b "ab" | join " " | wrap 4 }}`,
+			want:     nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md := Markdown{
+				template: template.Must(template.New("README.tpl.md").Funcs(funcs).Parse(tt.template)),
+				tagMap:   tags,
+			}
+
+			lines, err := md.TagLines()
+			assert.NoError(t, err)
+
+			var got [][]string
+			for _, lineTags := range lines {
+				var placeholders []string
+				for _, current := range lineTags {
+					placeholders = append(placeholders, current.Placeholder())
+				}
+				got = append(got, placeholders)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
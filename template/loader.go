@@ -1,47 +1,133 @@
 package template
 
 import (
+	"errors"
+	"fmt"
 	"io/fs"
+	"path/filepath"
 	"sort"
 	"strings"
 
 	"github.com/Tiffinger-Thiel-GmbH/atwhy/core/tag"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/diagnostic"
 	"github.com/spf13/afero"
 )
 
 type Loader struct {
 	FS                afero.Fs
 	ProjectPathPrefix string
+
+	// SourceLink is passed to all templates, see Markdown.SourceLink.
+	SourceLink SourceLinkFunc
+
+	// Folder is the path of the FS relative to the project.
+	// It is only used to report the correct file paths.
+	Folder string
+
+	// Reporter receives warnings about the templates.
+	// It may be nil.
+	Reporter diagnostic.Reporter
+
+	// DuplicateSeverity is used to report placeholders which are used by more than one tag.
+	// If it is diagnostic.SeverityError, Load fails if there is any duplicate.
+	// Default is diagnostic.SeverityWarning.
+	DuplicateSeverity diagnostic.Severity
+
+	// MissingSeverity is used to report templates which reference tags that do not exist.
+	// If it is diagnostic.SeverityError, Load fails if there is any missing tag.
+	// Default is diagnostic.SeverityError.
+	MissingSeverity diagnostic.Severity
 }
 
+var ErrDuplicatePlaceholder = errors.New("some placeholders are used by more than one tag")
+
 type mappedTags = map[string]tag.Tag
 
-func createTagMap(tags []tag.Tag) mappedTags {
+// createTagMap maps the tags by their placeholders.
+// The tags are processed ordered by their file and line, so that the result
+// does not depend on the order in which the files were loaded.
+// Duplicates are reported and only the first of them is used.
+// Tags which should be accumulated are combined into a tag.Accumulated.
+func (l Loader) createTagMap(tags []tag.Tag) (mappedTags, error) {
+	sorted := make([]tag.Tag, len(tags))
+	copy(sorted, tags)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].File() != sorted[j].File() {
+			return sorted[i].File() < sorted[j].File()
+		}
+		return sorted[i].Line() < sorted[j].Line()
+	})
+
+	severity := l.DuplicateSeverity
+	if severity == "" {
+		severity = diagnostic.SeverityWarning
+	}
+
+	duplicates := 0
 	tagMap := make(map[string]tag.Tag)
 
-	for _, t := range tags {
-		tagMap[t.Placeholder()] = t
+	for _, t := range sorted {
+		existing, found := tagMap[t.Placeholder()]
+		if !found {
+			if t.Accumulate() {
+				tagMap[t.Placeholder()] = tag.Accumulated{t}
+			} else {
+				tagMap[t.Placeholder()] = t
+			}
+			continue
+		}
+
+		if accumulated, ok := existing.(tag.Accumulated); ok && t.Accumulate() {
+			tagMap[t.Placeholder()] = append(accumulated, t)
+			continue
+		}
+
+		duplicates++
+		diagnostic.Report(l.Reporter, diagnostic.Diagnostic{
+			Severity: severity,
+			Code:     "duplicate-placeholder",
+			File:     t.File(),
+			Line:     t.Line(),
+			Message: fmt.Sprintf("the placeholder %v is already used at %v:%v (prefix it with + in all places to combine them)",
+				t.Placeholder(), existing.File(), existing.Line()),
+		})
+	}
+
+	if duplicates > 0 && severity == diagnostic.SeverityError {
+		return nil, fmt.Errorf("%w (%v found)", ErrDuplicatePlaceholder, duplicates)
 	}
 
-	return tagMap
+	return tagMap, nil
 }
 
 // Load templates from the Loader.FS.
 func (l Loader) Load(tags []tag.Tag) ([]Markdown, error) {
 	var res []Markdown
 
-	mappedTags := createTagMap(tags)
+	mappedTags, err := l.createTagMap(tags)
+	if err != nil {
+		return nil, err
+	}
 
-	err := afero.Walk(l.FS, "", func(path string, info fs.FileInfo, err error) error {
+	partials, err := l.loadPartials()
+	if err != nil {
+		return nil, err
+	}
+
+	err = afero.Walk(l.FS, "", func(path string, info fs.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 		if info.IsDir() {
+			// The partials are only used inside of the other templates.
+			if path == PartialsFolder {
+				return filepath.SkipDir
+			}
 			return nil
 		}
 
-		if strings.HasSuffix(path, ".tpl.md") {
-			newTpl, err := readTemplate(l.FS, l.ProjectPathPrefix, path, mappedTags)
+		if strings.HasSuffix(path, templateSuffix) {
+			newTpl, err := l.readTemplate(path, mappedTags, partials)
 			if err != nil {
 				return err
 			}
@@ -65,5 +151,10 @@ func (l Loader) Load(tags []tag.Tag) ([]Markdown, error) {
 		return res[i].Header.Meta.Title < res[j].Header.Meta.Title
 	})
 
+	err = l.analyze(res, mappedTags)
+	if err != nil {
+		return nil, err
+	}
+
 	return res, nil
 }
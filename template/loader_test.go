@@ -6,11 +6,13 @@ import (
 	"fmt"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/spf13/afero"
 
 	"github.com/Tiffinger-Thiel-GmbH/atwhy/core/tag"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/diagnostic"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -31,6 +33,30 @@ func (f fakeTag) Placeholder() string {
 	return f.name
 }
 
+func (f fakeTag) File() string {
+	return ""
+}
+
+func (f fakeTag) Line() int {
+	return 0
+}
+
+func (f fakeTag) Column() int {
+	return 0
+}
+
+func (f fakeTag) EndLine() int {
+	return 0
+}
+
+func (f fakeTag) EndColumn() int {
+	return 0
+}
+
+func (f fakeTag) Accumulate() bool {
+	return false
+}
+
 func Test_createTagMap(t *testing.T) {
 	type args struct {
 		tags []tag.Tag
@@ -76,13 +102,119 @@ func Test_createTagMap(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := createTagMap(tt.args.tags); !reflect.DeepEqual(got, tt.want) {
+			got, err := Loader{}.createTagMap(tt.args.tags)
+			assert.NoError(t, err)
+			if !reflect.DeepEqual(got, tt.want) {
 				assert.Equal(t, tt.want, got)
 			}
 		})
 	}
 }
 
+func Test_createTagMap_duplicates(t *testing.T) {
+	newTag := func(placeholder, value, file string, line int) tag.Tag {
+		prefix := ""
+		if strings.HasPrefix(placeholder, "+") {
+			prefix = "+"
+			placeholder = placeholder[1:]
+		}
+		res, err := tag.Doc(tag.Raw{
+			Type:        tag.TypeDoc,
+			Placeholder: placeholder,
+			Accumulate:  prefix == "+",
+			Filename:    file,
+			Line:        line,
+			Value:       "@WHY " + prefix + placeholder + "\n" + value,
+		})
+		assert.NoError(t, err)
+		return res
+	}
+
+	tests := []struct {
+		name            string
+		severity        diagnostic.Severity
+		tags            []tag.Tag
+		want            map[string]string
+		wantErr         assert.ErrorAssertionFunc
+		wantDiagnostics []string
+	}{
+		{
+			name: "the first duplicate wins",
+			tags: []tag.Tag{
+				newTag("dup", "b", "b.go", 1),
+				newTag("dup", "a2", "a.go", 11),
+				newTag("dup", "a1", "a.go", 3),
+			},
+			want:    map[string]string{"dup": "a1"},
+			wantErr: assert.NoError,
+			wantDiagnostics: []string{
+				"a.go:11: warning: the placeholder dup is already used at a.go:3 (prefix it with + in all places to combine them)",
+				"b.go:1: warning: the placeholder dup is already used at a.go:3 (prefix it with + in all places to combine them)",
+			},
+		},
+		{
+			name:     "duplicates can be errors",
+			severity: diagnostic.SeverityError,
+			tags: []tag.Tag{
+				newTag("dup", "a", "a.go", 1),
+				newTag("dup", "b", "b.go", 1),
+			},
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrDuplicatePlaceholder)
+			},
+			wantDiagnostics: []string{
+				"b.go:1: error: the placeholder dup is already used at a.go:1 (prefix it with + in all places to combine them)",
+			},
+		},
+		{
+			name: "accumulating tags are combined",
+			tags: []tag.Tag{
+				newTag("+list", "b", "b.go", 1),
+				newTag("+list", "a", "a.go", 1),
+			},
+			want:    map[string]string{"list": "a" + tag.HardNewLine + "b"},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "mixing accumulating and normal tags is a duplicate",
+			tags: []tag.Tag{
+				newTag("+list", "a", "a.go", 1),
+				newTag("list", "b", "b.go", 1),
+			},
+			want:    map[string]string{"list": "a"},
+			wantErr: assert.NoError,
+			wantDiagnostics: []string{
+				"b.go:1: warning: the placeholder list is already used at a.go:1 (prefix it with + in all places to combine them)",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diagnostics := &diagnostic.Collector{}
+			got, err := Loader{Reporter: diagnostics, DuplicateSeverity: tt.severity}.createTagMap(tt.tags)
+			if !tt.wantErr(t, err) {
+				return
+			}
+
+			var gotDiagnostics []string
+			for _, d := range diagnostics.Diagnostics() {
+				gotDiagnostics = append(gotDiagnostics, d.String())
+			}
+			assert.Equal(t, tt.wantDiagnostics, gotDiagnostics)
+
+			if err != nil {
+				return
+			}
+
+			gotValues := make(map[string]string)
+			for placeholder, t := range got {
+				gotValues[placeholder] = t.String()
+			}
+			assert.Equal(t, tt.want, gotValues)
+		})
+	}
+}
+
 func testFS() afero.Fs {
 	memFS := afero.NewMemMapFs()
 	_ = memFS.MkdirAll("docu/subdocu", 0755)
@@ -138,6 +270,7 @@ func TestLoader_Load(t *testing.T) {
 						Meta: MetaData{Title: "Docu"},
 					},
 					tagMap: mappedTags{},
+					file:   "docu/Docu.tpl.md",
 				},
 				{
 					ID:                buildTestId("docu/subdocu/Docu2.tpl.md"),
@@ -148,6 +281,7 @@ func TestLoader_Load(t *testing.T) {
 						Meta: MetaData{Title: "Docu2"},
 					},
 					tagMap: mappedTags{},
+					file:   "docu/subdocu/Docu2.tpl.md",
 				},
 				{
 					ID:                buildTestId("README.tpl.md"),
@@ -158,6 +292,7 @@ func TestLoader_Load(t *testing.T) {
 						Meta: MetaData{Title: "README"},
 					},
 					tagMap: mappedTags{},
+					file:   "README.tpl.md",
 				},
 			},
 			wantErr: assert.NoError,
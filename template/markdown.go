@@ -7,17 +7,13 @@ import (
 	"errors"
 	"io"
 	"path/filepath"
-	"sort"
 	"strings"
 	"text/template"
 	"time"
 
 	"github.com/Tiffinger-Thiel-GmbH/atwhy/core/tag"
-	"golang.org/x/text/collate"
-	"golang.org/x/text/language"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/diagnostic"
 	"gopkg.in/yaml.v2"
-
-	"github.com/spf13/afero"
 )
 
 const templateSuffix = ".tpl.md"
@@ -39,6 +35,9 @@ var (
 // # Additional configuration for the `atwhy serve` command.
 // server:
 //   index: true # default: false
+//
+// # A partial which is used as the skeleton of this page, see "Partials and layouts".
+// layout: page # default: no layout
 // ---
 // # Your Markdown starts here
 //
@@ -54,6 +53,10 @@ type Header struct {
 	Meta MetaData `yaml:"meta"`
 
 	Server ServerData `yaml:"server"`
+
+	// Layout is the name of a partial which is executed instead of the template.
+	// The template fills the blocks of the layout.
+	Layout string `yaml:"layout"`
 }
 
 type MetaData struct {
@@ -89,26 +92,59 @@ type Markdown struct {
 	Path              string
 	Header            Header
 
+	// ProjectLinks is called with each project file linked by .Project while executing.
+	// It may be nil.
+	ProjectLinks func(file string)
+
+	// SourceLink creates the links of .Project instead of the ProjectPathPrefix (e.g. permalinks to a git host).
+	// It may be nil.
+	SourceLink SourceLinkFunc
+
+	// Now is used as the current date time of {{ .Now }} if it is set.
+	// It is the time of the execution otherwise.
+	Now time.Time
+
+	// template is executed, it is either the template itself or its layout.
 	template *template.Template
 	tagMap   map[string]tag.Tag
+
+	// file is the path of the template relative to the project.
+	file string
+	// lineOffset is the number of lines before the template body.
+	lineOffset int
+
+	// partialFiles maps the names of the parsed partials to their files relative to the project.
+	partialFiles map[string]string
 }
 
-func readTemplate(sysfs afero.Fs, projectPathPrefix string, path string, tags mappedTags) (Markdown, error) {
-	file, err := sysfs.Open(path)
+// readFile reads the file from the Loader.FS.
+func (l Loader) readFile(path string) ([]byte, error) {
+	file, err := l.FS.Open(path)
 	if err != nil {
-		return Markdown{}, err
+		return nil, err
 	}
 	defer file.Close()
 
-	tplData, err := io.ReadAll(file)
+	data, err := io.ReadAll(file)
 	if err != nil {
-		return Markdown{}, err
+		return nil, err
 	}
 
 	// Windows compatibility:
-	tplData = bytes.ReplaceAll(tplData, []byte("\r\n"), []byte("\n"))
+	return bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n")), nil
+}
+
+func (l Loader) readTemplate(path string, tags mappedTags, partials partials) (Markdown, error) {
+	// The path used in the diagnostics, relative to the project.
+	reportFile := l.reportFile(path)
+
+	tplData, err := l.readFile(path)
+	if err != nil {
+		return Markdown{}, err
+	}
 
 	var body string
+	var headerData []byte
 	header := Header{}
 
 	// No Header exists because the first line was no "---"
@@ -118,26 +154,64 @@ func readTemplate(sysfs afero.Fs, projectPathPrefix string, path string, tags ma
 
 		if len(splitted) == 3 {
 			body = string(splitted[2])
+			headerData = splitted[1]
 
-			err = yaml.Unmarshal(splitted[1], &header)
+			// Report unknown keys as they are most likely typos.
+			err = yaml.UnmarshalStrict(splitted[1], &header)
 			if err != nil {
-				return Markdown{}, err
+				header = Header{}
+				if nonStrictErr := yaml.Unmarshal(splitted[1], &header); nonStrictErr != nil {
+					return Markdown{}, headerDiagnostics(reportFile, diagnostic.SeverityError, nonStrictErr)[0]
+				}
+
+				for _, d := range headerDiagnostics(reportFile, diagnostic.SeverityWarning, err) {
+					diagnostic.Report(l.Reporter, d)
+				}
 			}
 		} else {
-			return Markdown{}, ErrMissingBody
+			return Markdown{}, diagnostic.Diagnostic{
+				Severity: diagnostic.SeverityError,
+				Code:     "invalid-header",
+				File:     reportFile,
+				Line:     1,
+				Message:  ErrMissingBody.Error(),
+				Err:      ErrMissingBody,
+			}
 		}
 	} else {
 		body = string(tplData)
 	}
 
+	// The number of lines before the body, to report the correct lines.
+	lineOffset := bytes.Count(tplData[:len(tplData)-len(body)], []byte("\n"))
+
 	filename := filepath.Base(path)
 
 	id := md5.Sum([]byte(filepath.ToSlash(path)))
-	tpl, err := template.New(filename).Parse(body)
 
+	// Each template gets its own copy of the partials, as it may redefine their blocks.
+	set, err := partials.template.Clone()
 	if err != nil {
 		return Markdown{}, err
 	}
+	tpl, err := set.New(filename).Parse(body)
+	if err != nil {
+		return Markdown{}, templateDiagnostic(reportFile, lineOffset, err)
+	}
+
+	if header.Layout != "" {
+		tpl, err = applyLayout(tpl, header.Layout)
+		if err != nil {
+			return Markdown{}, diagnostic.Diagnostic{
+				Severity: diagnostic.SeverityError,
+				Code:     "invalid-header",
+				File:     reportFile,
+				Line:     headerKeyLine(headerData, "layout"),
+				Message:  err.Error(),
+				Err:      err,
+			}
+		}
+	}
 
 	if header.Meta.Title == "" {
 		header.Meta.Title = strings.TrimSuffix(filename, templateSuffix)
@@ -145,28 +219,64 @@ func readTemplate(sysfs afero.Fs, projectPathPrefix string, path string, tags ma
 
 	markdownTemplate := Markdown{
 		ID:                "page-" + hex.EncodeToString(id[:]),
-		ProjectPathPrefix: projectPathPrefix,
+		ProjectPathPrefix: l.ProjectPathPrefix,
+		SourceLink:        l.SourceLink,
 		Name:              strings.TrimSuffix(filepath.Base(path), templateSuffix),
 		Path:              filepath.Dir(path),
 
 		Header:   header,
 		template: tpl,
 		tagMap:   tags,
+
+		file:         reportFile,
+		lineOffset:   lineOffset,
+		partialFiles: partials.files,
 	}
 
 	return markdownTemplate, nil
 }
 
+// SourceLinkFunc creates the link to a project file.
+// line and endLine are the lines to link, they are 0 if they are not known.
+type SourceLinkFunc func(file string, line int, endLine int) string
+
 type data struct {
 	Tag           map[string]tag.Tag
 	Meta          MetaData
-	Now           string
+	now           time.Time
 	projectPrefix string
+	projectLinks  func(file string)
+	sourceLink    SourceLinkFunc
 
 	isPostprocessing bool
 }
 
-func (d data) Project(file string) string {
+// Now returns the current date time.
+// The format can be passed as Go layout, the default is time.RFC822Z.
+func (d data) Now(layout ...string) string {
+	if len(layout) > 0 {
+		return d.now.Format(layout[0])
+	}
+	return d.now.Format(time.RFC822Z)
+}
+
+// Project returns the link to the given project file.
+// The optional lines (the first and the last line) are only used by the sourceLink.
+func (d data) Project(file string, lines ...int) string {
+	if d.sourceLink != nil {
+		line, endLine := 0, 0
+		if len(lines) > 0 {
+			line, endLine = lines[0], lines[0]
+		}
+		if len(lines) > 1 {
+			endLine = lines[1]
+		}
+		return d.sourceLink(file, line, endLine)
+	}
+
+	if d.projectLinks != nil {
+		d.projectLinks(file)
+	}
 	return filepath.ToSlash(filepath.Join(d.projectPrefix, file))
 }
 
@@ -199,35 +309,35 @@ func (d data) Escape(value string) string {
 // sorts them alphanumerically and then concatenates them
 // using hard-newlines.
 func (d data) Group(prefix string) string {
-	// Get all tags with that prefix.
-	var tagGroup []tag.Tag
-	for currentTagKey, currentTag := range d.Tag {
-		if strings.HasPrefix(currentTagKey, prefix) {
-			tagGroup = append(tagGroup, currentTag)
-		}
-	}
-
-	col := collate.New(language.Make("en-US"), collate.Numeric)
-
-	sort.Slice(tagGroup, func(i, j int) bool {
-		return col.CompareString(tagGroup[i].Placeholder(), tagGroup[j].Placeholder()) == -1
-	})
-
 	var result string
-	for _, currentTag := range tagGroup {
+	for _, currentTag := range d.Tags().WithPrefix(prefix) {
 		result += currentTag.String() + "  \n"
 	}
 
 	return result
 }
 
+// Tags returns all tags sorted by their placeholder, so that they can be queried.
+func (d data) Tags() Tags {
+	return newTags(d.Tag)
+}
+
+// File returns the path of the template relative to the project.
+func (t Markdown) File() string {
+	return t.file
+}
+
 // Execute the template
 func (t Markdown) Execute(writer io.Writer) error {
 
 	// @WHY doc_template_usage1_possible_tags
 	// __Possible template values are:__
 	// * Any Tag from the project: `{{"{{ .Tag.example_tag }}"}}`
-	// * Current date time: `{{"{{ .Now }}"}}`
+	// * The source location of a tag: `{{"{{ .Tag.example_tag.File }}"}}`, `{{"{{ .Tag.example_tag.Line }}"}}` and `{{"{{ .Tag.example_tag.Column }}"}}`
+	//   as well as `{{"{{ .Tag.example_tag.EndLine }}"}}` and `{{"{{ .Tag.example_tag.EndColumn }}"}}`.
+	//   The file is relative to the project, lines and columns start at 1.
+	//   This can be used for "defined in" footers: `{{ .Escape "[source]({{ .Project .Tag.example_tag.File }})" }}`
+	// * Current date time: `{{"{{ .Now }}"}}` or with a custom format `{{"{{ .Now \"2006-01-02\" }}"}}`
 	// * Metadata from the yaml header: `{{"{{ .Meta.Title }}"}}`
 	// * Conversion of links to project-files (also in serve-mode): `{{"{{ .Project \"my/file/in/the/project.go\" }}"}}`
 	//   You need to use that if you want to generate links to actual files in your project.
@@ -237,12 +347,19 @@ func (t Markdown) Execute(writer io.Writer) error {
 	//   e.g. it matches `\@WHY tag_name_prefix0`, `\@WHY tag_name_prefix1`, ...
 	//   These tags get sorted alphanumeric.
 
+	now := t.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
 	d := data{
 		Tag:  t.tagMap,
-		Now:  time.Now().Format(time.RFC822Z), // TODO: add this as function instead of as value to be able to pass any format.
+		now:  now,
 		Meta: t.Header.Meta,
 
 		projectPrefix: t.ProjectPathPrefix,
+		projectLinks:  t.ProjectLinks,
+		sourceLink:    t.SourceLink,
 	}
 
 	buf := bytes.NewBufferString("")
@@ -255,14 +372,29 @@ func (t Markdown) Execute(writer io.Writer) error {
 
 	// And then execute the postprocessing template.
 	// E.g. it can process the {{ .Project }} even if the links are inside the tags.
-	postProcessTemplate, err := template.New("postProcessing.md").Parse(buf.String())
+	return postProcess(writer, buf.String(), d)
+}
+
+// postProcess executes the already rendered text a second time.
+func postProcess(writer io.Writer, text string, d data) error {
+	postProcessTemplate, err := template.New("postProcessing.md").Funcs(funcs).Parse(text)
 	if err != nil {
 		return err
 	}
 	// Do not allow tags in this step as it would create bad edge cases.
 	d.Tag = map[string]tag.Tag{}
 	d.isPostprocessing = true
-	err = postProcessTemplate.Execute(writer, d)
+	return postProcessTemplate.Execute(writer, d)
+}
 
-	return err
+// RenderValue returns the value of a tag as it is shown in the templates,
+// e.g. with the resolved {{ .Project }} links and {{ .Escape }}.
+// The links to project files are prefixed with the projectPathPrefix.
+func RenderValue(value string, projectPathPrefix string) (string, error) {
+	var buf bytes.Buffer
+	err := postProcess(&buf, value, data{
+		now:           time.Now(),
+		projectPrefix: projectPathPrefix,
+	})
+	return buf.String(), err
 }
@@ -7,6 +7,7 @@ import (
 	"text/template"
 
 	"github.com/Tiffinger-Thiel-GmbH/atwhy/core/tag"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/diagnostic"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 )
@@ -47,6 +48,7 @@ func Test_readTemplate(t *testing.T) {
 				Header: Header{
 					Meta: MetaData{Title: "README"},
 				},
+				file: "README.tpl.md",
 			},
 			wantErr: assert.NoError,
 		},
@@ -72,6 +74,8 @@ server:
 					Meta:   MetaData{Title: "Test Readme"},
 					Server: ServerData{Index: true},
 				},
+				file:       "README.tpl.md",
+				lineOffset: 6,
 			},
 			wantErr: assert.NoError,
 		},
@@ -90,6 +94,7 @@ server:
 				Header: Header{
 					Meta: MetaData{Title: "README"},
 				},
+				file: "README.tpl.md",
 			},
 			wantErr: assert.NoError,
 		},
@@ -145,7 +150,11 @@ server:
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := readTemplate(tt.args.sysfs, tt.args.projectPathPrefix, tt.args.path, tt.args.tags)
+			l := Loader{
+				FS:                tt.args.sysfs,
+				ProjectPathPrefix: tt.args.projectPathPrefix,
+			}
+			got, err := l.readTemplate(tt.args.path, tt.args.tags, newPartials())
 			if !tt.wantErr(t, err, fmt.Sprintf("readTemplate(%v, %v, %v, %v)", tt.args.sysfs, tt.args.projectPathPrefix, tt.args.path, tt.args.tags)) {
 				return
 			}
@@ -160,16 +169,67 @@ server:
 	}
 }
 
+func Test_readTemplate_diagnostics(t *testing.T) {
+	t.Run("unknown header keys are reported as warning", func(t *testing.T) {
+		diagnostics := &diagnostic.Collector{}
+		l := Loader{
+			FS: testFileFS("README.tpl.md", []byte(`---
+meta:
+  titel: Test Readme
+---
+# Hello World!`)),
+			Folder:   "templates",
+			Reporter: diagnostics,
+		}
+
+		got, err := l.readTemplate("README.tpl.md", nil, newPartials())
+		assert.NoError(t, err)
+		assert.Equal(t, "README", got.Header.Meta.Title)
+		assert.Equal(t, []diagnostic.Diagnostic{
+			{
+				Severity: diagnostic.SeverityWarning,
+				Code:     "unknown-header-key",
+				File:     "templates/README.tpl.md",
+				Line:     3,
+				Message:  `unknown key "titel" in the template header`,
+				Err:      diagnostics.Diagnostics()[0].Err,
+			},
+		}, diagnostics.Diagnostics())
+	})
+
+	t.Run("template errors contain the line in the file", func(t *testing.T) {
+		l := Loader{
+			FS: testFileFS("README.tpl.md", []byte(`---
+meta:
+  title: Test Readme
+---
+# Hello World!
+{{ .Tag.foo }
+`)),
+			Folder: "templates",
+		}
+
+		_, err := l.readTemplate("README.tpl.md", nil, newPartials())
+		var d diagnostic.Diagnostic
+		assert.ErrorAs(t, err, &d)
+		assert.Equal(t, diagnostic.SeverityError, d.Severity)
+		assert.Equal(t, "templates/README.tpl.md", d.File)
+		assert.Equal(t, 6, d.Line)
+		assert.Equal(t, `unexpected "}" in operand`, d.Message)
+	})
+}
+
 func Test_data_Project(t *testing.T) {
 	type fields struct {
 		Tag              map[string]tag.Tag
 		Meta             MetaData
-		Now              string
 		projectPrefix    string
+		sourceLink       SourceLinkFunc
 		isPostprocessing bool
 	}
 	type args struct {
 		file string
+		line []int
 	}
 	tests := []struct {
 		name   string
@@ -187,17 +247,67 @@ func Test_data_Project(t *testing.T) {
 			},
 			want: "prefix/filename.md",
 		},
+		{
+			name: "ignores the line",
+			fields: fields{
+				projectPrefix: "prefix",
+			},
+			args: args{
+				file: "main.go",
+				line: []int{5},
+			},
+			want: "prefix/main.go",
+		},
+		{
+			name: "uses the source link",
+			fields: fields{
+				projectPrefix: "prefix",
+				sourceLink: func(file string, line int, endLine int) string {
+					return fmt.Sprintf("https://example.com/%v#L%v-L%v", file, line, endLine)
+				},
+			},
+			args: args{
+				file: "main.go",
+				line: []int{5},
+			},
+			want: "https://example.com/main.go#L5-L5",
+		},
+		{
+			name: "uses the source link with a range",
+			fields: fields{
+				sourceLink: func(file string, line int, endLine int) string {
+					return fmt.Sprintf("https://example.com/%v#L%v-L%v", file, line, endLine)
+				},
+			},
+			args: args{
+				file: "main.go",
+				line: []int{5, 9},
+			},
+			want: "https://example.com/main.go#L5-L9",
+		},
+		{
+			name: "uses the source link without line",
+			fields: fields{
+				sourceLink: func(file string, line int, endLine int) string {
+					return fmt.Sprintf("https://example.com/%v#L%v-L%v", file, line, endLine)
+				},
+			},
+			args: args{
+				file: "main.go",
+			},
+			want: "https://example.com/main.go#L0-L0",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			d := data{
 				Tag:              tt.fields.Tag,
 				Meta:             tt.fields.Meta,
-				Now:              tt.fields.Now,
 				projectPrefix:    tt.fields.projectPrefix,
+				sourceLink:       tt.fields.sourceLink,
 				isPostprocessing: tt.fields.isPostprocessing,
 			}
-			assert.Equalf(t, tt.want, d.Project(tt.args.file), "Project(%v)", tt.args.file)
+			assert.Equalf(t, tt.want, d.Project(tt.args.file, tt.args.line...), "Project(%v, %v)", tt.args.file, tt.args.line)
 		})
 	}
 }
@@ -206,7 +316,6 @@ func Test_data_Escape(t *testing.T) {
 	type fields struct {
 		Tag              map[string]tag.Tag
 		Meta             MetaData
-		Now              string
 		projectPrefix    string
 		isPostprocessing bool
 	}
@@ -265,7 +374,6 @@ func Test_data_Escape(t *testing.T) {
 			d := data{
 				Tag:              tt.fields.Tag,
 				Meta:             tt.fields.Meta,
-				Now:              tt.fields.Now,
 				projectPrefix:    tt.fields.projectPrefix,
 				isPostprocessing: tt.fields.isPostprocessing,
 			}
@@ -334,6 +442,26 @@ func TestMarkdown_Execute(t1 *testing.T) {
 			wantWriter: "Title: \"Readme\"",
 			wantErr:    assert.NoError,
 		},
+		{
+			name: "Render the source location of a tag",
+			fields: fields{
+				template: template.Must(template.New("").Parse("defined in {{ .Tag.something.File }}:{{ .Tag.something.Line }}:{{ .Tag.something.Column }}-{{ .Tag.something.EndLine }}:{{ .Tag.something.EndColumn }}")),
+				tagMap: map[string]tag.Tag{
+					"something": mustTag(tag.Doc(tag.Raw{
+						Type:        tag.TypeDoc,
+						Placeholder: "something",
+						Filename:    "main.go",
+						Value:       "@WHY something\nvalue",
+						Line:        3,
+						Column:      4,
+						EndLine:     4,
+						EndColumn:   9,
+					})),
+				},
+			},
+			wantWriter: "defined in main.go:3:4-4:9",
+			wantErr:    assert.NoError,
+		},
 		{
 			name: "Render .Escape",
 			fields: fields{
@@ -431,7 +559,6 @@ func Test_data_Group(t *testing.T) {
 	type fields struct {
 		Tag              map[string]tag.Tag
 		Meta             MetaData
-		Now              string
 		projectPrefix    string
 		isPostprocessing bool
 	}
@@ -515,7 +642,6 @@ b-0
 			d := data{
 				Tag:              tt.fields.Tag,
 				Meta:             tt.fields.Meta,
-				Now:              tt.fields.Now,
 				projectPrefix:    tt.fields.projectPrefix,
 				isPostprocessing: tt.fields.isPostprocessing,
 			}
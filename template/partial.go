@@ -0,0 +1,126 @@
+package template
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/spf13/afero"
+)
+
+// PartialsFolder is the folder inside of the templates folder which contains the partials.
+const PartialsFolder = "_partials"
+
+// contentBlock is the name of the block which gets the body of a template using a layout.
+const contentBlock = "content"
+
+var ErrUnknownLayout = errors.New("the layout does not exist")
+
+// @WHY doc_template_usage4_partials
+//
+// __Partials and layouts:__
+// All templates in the folder `_partials` inside of the templates folder are available in every template.
+// They are not generated on their own and have no yaml header.
+// A partial is used by its filename without `.tpl.md`, e.g. `_partials/footer.tpl.md` with `{{"{{ template \"footer\" . }}"}}`.
+// Templates defined inside of the partials with `{{"{{ define \"name\" }}"}}` can be used the same way.
+//
+// A template can use a partial as layout by setting `layout: name` in its header.
+// The layout is then generated instead of the template and the template fills the blocks of the layout:
+// * The body of the template is available as `{{"{{ template \"content\" . }}"}}`.
+// * Other blocks (`{{"{{ block \"name\" . }}default{{ end }}"}}`) are replaced with `{{"{{ define \"name\" }}...{{ end }}"}}`.
+
+// partials contains the parsed partials which are shared by all templates.
+type partials struct {
+	template *template.Template
+
+	// files maps the name of each partial to its file relative to the project.
+	// It is nil if there are no partials.
+	files map[string]string
+}
+
+func newPartials() partials {
+	return partials{template: template.New("").Funcs(funcs)}
+}
+
+// reportFile returns the path used in the diagnostics, relative to the project.
+func (l Loader) reportFile(path string) string {
+	return filepath.ToSlash(filepath.Join(l.Folder, path))
+}
+
+// loadPartials parses all templates of the PartialsFolder.
+// It returns empty partials if the folder does not exist.
+func (l Loader) loadPartials() (partials, error) {
+	result := newPartials()
+
+	exists, err := afero.DirExists(l.FS, PartialsFolder)
+	if err != nil || !exists {
+		return result, err
+	}
+
+	err = afero.Walk(l.FS, PartialsFolder, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, templateSuffix) {
+			return nil
+		}
+
+		name := strings.TrimSuffix(filepath.Base(path), templateSuffix)
+		reportFile := l.reportFile(path)
+		if existing, ok := result.files[name]; ok {
+			return templateDiagnostic(reportFile, 0, fmt.Errorf("the partial %v is already defined in %v", name, existing))
+		}
+
+		body, err := l.readFile(path)
+		if err != nil {
+			return err
+		}
+
+		_, err = result.template.New(name).Parse(string(body))
+		if err != nil {
+			return templateDiagnostic(reportFile, 0, err)
+		}
+		if result.files == nil {
+			result.files = make(map[string]string)
+		}
+		result.files[name] = reportFile
+		return nil
+	})
+
+	return result, err
+}
+
+// applyLayout returns the layout which has to be executed instead of the template.
+// The body of the template is added as contentBlock, if it is not empty.
+func applyLayout(tpl *template.Template, layout string) (*template.Template, error) {
+	result := tpl.Lookup(layout)
+	if result == nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownLayout, layout)
+	}
+
+	if tpl.Tree != nil && !parse.IsEmptyTree(tpl.Tree.Root) {
+		_, err := tpl.AddParseTree(contentBlock, tpl.Tree)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// headerKeyLine returns the line of the top level key in the file.
+// The header always starts in the second line of the file.
+// It returns 1 (the start of the header) if the key is not found.
+func headerKeyLine(header []byte, key string) int {
+	for i, line := range bytes.Split(header, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte(key+":")) {
+			return i + 2
+		}
+	}
+	return 1
+}
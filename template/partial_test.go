@@ -0,0 +1,138 @@
+package template
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/core/tag"
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/diagnostic"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testPartialsFS(files map[string]string) afero.Fs {
+	memFS := afero.NewMemMapFs()
+	for name, content := range files {
+		_ = afero.WriteFile(memFS, name, []byte(content), 0777)
+	}
+	return memFS
+}
+
+func TestLoader_Load_partials(t *testing.T) {
+	partials := map[string]string{
+		"_partials/footer.tpl.md": "Footer {{ .Tag.footer }}\n",
+		"_partials/page.tpl.md":   "# {{ .Meta.Title }}\n{{ template \"content\" . }}\n{{ block \"contributing\" . }}Default contributing{{ end }}\n{{ template \"footer\" . }}",
+	}
+	tags := []tag.Tag{fakeTag{name: "footer", value: "text"}, fakeTag{name: "intro", value: "Hello"}}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantErr  assert.ErrorAssertionFunc
+	}{
+		{
+			name:     "partial",
+			template: "# Readme\n{{ template \"footer\" . }}",
+			want:     "# Readme\nFooter text\n",
+			wantErr:  assert.NoError,
+		},
+		{
+			name:     "layout with default blocks",
+			template: "---\nlayout: page\n---\n{{ .Tag.intro }}",
+			want:     "# README\nHello\nDefault contributing\nFooter text\n",
+			wantErr:  assert.NoError,
+		},
+		{
+			name:     "layout with filled blocks",
+			template: "---\nlayout: page\n---\n{{ define \"content\" }}Content{{ end }}\n{{ define \"contributing\" }}Contributing{{ end }}\n",
+			want:     "# README\nContent\nContributing\nFooter text\n",
+			wantErr:  assert.NoError,
+		},
+		{
+			name:     "unknown layout",
+			template: "---\nmeta:\n  title: Readme\nlayout: unknown\n---\n# Readme",
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				var d diagnostic.Diagnostic
+				return assert.ErrorIs(t, err, ErrUnknownLayout, i...) &&
+					assert.ErrorAs(t, err, &d, i...) &&
+					assert.Equal(t, "templates/README.tpl.md:4: error: the layout does not exist: unknown", d.String(), i...)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := map[string]string{"README.tpl.md": tt.template}
+			for name, content := range partials {
+				files[name] = content
+			}
+
+			got, err := Loader{FS: testPartialsFS(files), Folder: "templates"}.Load(tags)
+			if !tt.wantErr(t, err) || err != nil {
+				return
+			}
+
+			// The partials are no templates on their own.
+			require.Len(t, got, 1)
+
+			var buf bytes.Buffer
+			assert.NoError(t, got[0].Execute(&buf))
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestLoader_Load_partialErrors(t *testing.T) {
+	fs := testPartialsFS(map[string]string{
+		"_partials/footer.tpl.md": "Footer\n{{ .Tag.footer }\n",
+		"README.tpl.md":           "# Readme",
+	})
+
+	_, err := Loader{FS: fs, Folder: "templates"}.Load(nil)
+	var d diagnostic.Diagnostic
+	assert.ErrorAs(t, err, &d)
+	assert.Equal(t, `templates/_partials/footer.tpl.md:2: error: unexpected "}" in operand`, d.String())
+}
+
+func TestReferences_partials(t *testing.T) {
+	fs := testPartialsFS(map[string]string{
+		"_partials/footer.tpl.md": "Footer\n{{ .Tag.footer }}\n",
+		"README.tpl.md":           "---\nlayout: footer\n---\n{{ .Tag.intro }}",
+		"Other.tpl.md":            "{{ template \"footer\" . }}",
+	})
+
+	got, err := Loader{FS: fs, Folder: "templates", MissingSeverity: diagnostic.SeverityWarning}.Load(nil)
+	require.NoError(t, err)
+
+	// The references of the partial are only returned once.
+	// The layout of the README does not use its content, so the intro is not used by any template.
+	assert.Equal(t, []Reference{
+		{Placeholder: "footer", File: "templates/_partials/footer.tpl.md", Template: "templates/Other.tpl.md", Line: 2, Column: 8},
+		{Placeholder: "intro", File: "templates/README.tpl.md", Line: 4, Column: 8},
+	}, References(got))
+
+	// The used references are returned for each template using the partial.
+	assert.Equal(t, []Reference{
+		{Placeholder: "footer", File: "templates/_partials/footer.tpl.md", Template: "templates/Other.tpl.md", Line: 2, Column: 8},
+		{Placeholder: "footer", File: "templates/_partials/footer.tpl.md", Template: "templates/README.tpl.md", Line: 2, Column: 8},
+	}, UsedReferences(got))
+}
+
+func TestUsedReferences_unusedPartials(t *testing.T) {
+	fs := testPartialsFS(map[string]string{
+		"_partials/footer.tpl.md": "Footer\n{{ .Tag.footer }}\n",
+		"_partials/page.tpl.md":   "{{ template \"content\" . }}\n{{ block \"nav\" . }}{{ .Tag.nav }}{{ end }}",
+		"README.tpl.md":           "---\nlayout: page\n---\n{{ .Tag.intro }}",
+	})
+
+	got, err := Loader{FS: fs, Folder: "templates", MissingSeverity: diagnostic.SeverityWarning}.Load(nil)
+	require.NoError(t, err)
+
+	var placeholders []string
+	for _, r := range UsedReferences(got) {
+		assert.Equal(t, "templates/README.tpl.md", r.Template)
+		placeholders = append(placeholders, r.Placeholder)
+	}
+	assert.ElementsMatch(t, []string{"intro", "nav"}, placeholders)
+}
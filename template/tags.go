@@ -0,0 +1,122 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/core/tag"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+var ErrUnknownSortField = errors.New("the tags can only be sorted by placeholder, file or line")
+
+// @WHY doc_template_usage6_tags_query
+//
+// __Query tags:__
+// `{{"{{ .Tags }}"}}` is the list of all tags sorted alphanumeric by their placeholder.
+// It can be filtered and sorted to create e.g. one table row or list item for each tag:
+// * `{{"{{ .Tags.WithPrefix \"cli_flag_\" }}"}}` keeps the tags starting with the prefix.
+// * `{{"{{ .Tags.InFile \"cmd/*.go\" }}"}}` keeps the tags in files matching the [pattern](https://pkg.go.dev/path#Match).
+// * `{{"{{ .Tags.OfType \"CODE\" }}"}}` keeps the tags of the type (`DOC`, `LINK` or `CODE`).
+// * `{{"{{ .Tags.SortBy \"file\" }}"}}` sorts the tags by `placeholder`, `file` (and line) or `line` (and file).
+//
+// Each tag provides `{{"{{ .Placeholder }}"}}`, its body with `{{"{{ .String }}"}}`, `{{"{{ .File }}"}}` and `{{"{{ .Line }}"}}`.
+// The queries can be chained, e.g.:
+// ```markdown
+// {{"{{ range (.Tags.WithPrefix \"config_\").SortBy \"file\" }}"}}
+// * `{{"{{ .Placeholder }}"}}` ({{"{{ .File }}:{{ .Line }}"}}): {{"{{ .String }}"}}
+// {{"{{- end }}"}}
+// ```
+
+// Tags is a list of tags which can be queried inside of the templates.
+type Tags []tag.Tag
+
+// newTags returns all tags of the map sorted alphanumeric by their placeholder.
+func newTags(tagMap map[string]tag.Tag) Tags {
+	tags := make(Tags, 0, len(tagMap))
+	for _, t := range tagMap {
+		tags = append(tags, t)
+	}
+
+	sorted, _ := tags.SortBy("placeholder")
+	return sorted
+}
+
+// WithPrefix returns the tags whose placeholder starts with the prefix.
+func (t Tags) WithPrefix(prefix string) Tags {
+	return t.filter(func(current tag.Tag) bool {
+		return strings.HasPrefix(current.Placeholder(), prefix)
+	})
+}
+
+// InFile returns the tags whose file matches the pattern.
+// The pattern uses the syntax of path.Match and is relative to the project.
+func (t Tags) InFile(pattern string) (Tags, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid file pattern %q: %w", pattern, err)
+	}
+
+	return t.filter(func(current tag.Tag) bool {
+		matches, _ := path.Match(pattern, current.File())
+		return matches
+	}), nil
+}
+
+// OfType returns the tags of the type, ignoring the case.
+func (t Tags) OfType(tagType string) Tags {
+	return t.filter(func(current tag.Tag) bool {
+		return strings.EqualFold(string(current.Type()), tagType)
+	})
+}
+
+// SortBy returns the tags sorted by "placeholder", "file" or "line".
+// Tags in the same file are sorted by their line and tags in the same line by their file.
+func (t Tags) SortBy(field string) (Tags, error) {
+	byFile := func(a, b tag.Tag) bool {
+		if a.File() != b.File() {
+			return a.File() < b.File()
+		}
+		return a.Line() < b.Line()
+	}
+
+	var less func(a, b tag.Tag) bool
+	switch strings.ToLower(field) {
+	case "placeholder":
+		col := collate.New(language.Make("en-US"), collate.Numeric)
+		less = func(a, b tag.Tag) bool {
+			return col.CompareString(a.Placeholder(), b.Placeholder()) == -1
+		}
+	case "file":
+		less = byFile
+	case "line":
+		less = func(a, b tag.Tag) bool {
+			if a.Line() != b.Line() {
+				return a.Line() < b.Line()
+			}
+			return byFile(a, b)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownSortField, field)
+	}
+
+	sorted := make(Tags, len(t))
+	copy(sorted, t)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return less(sorted[i], sorted[j])
+	})
+	return sorted, nil
+}
+
+func (t Tags) filter(keep func(current tag.Tag) bool) Tags {
+	result := Tags{}
+	for _, current := range t {
+		if keep(current) {
+			result = append(result, current)
+		}
+	}
+	return result
+}
@@ -0,0 +1,110 @@
+package template
+
+import (
+	"bytes"
+	"testing"
+	"text/template"
+
+	"github.com/Tiffinger-Thiel-GmbH/atwhy/core/tag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testQueryTags(t *testing.T) map[string]tag.Tag {
+	raws := []tag.Raw{
+		{Type: tag.TypeDoc, Placeholder: "flag_10", Filename: "cmd/root.go", Line: 3, Value: "header\nFlag 10"},
+		{Type: tag.TypeDoc, Placeholder: "flag_2", Filename: "cmd/root.go", Line: 20, Value: "header\nFlag 2"},
+		{Type: tag.TypeDoc, Placeholder: "intro", Filename: "main.go", Line: 1, Value: "header\nIntro"},
+		{Type: tag.TypeCode, Placeholder: "flag_code", Filename: "cmd/sub/code.go", Line: 3, Value: "header\nx := 1"},
+	}
+
+	tags := make(map[string]tag.Tag)
+	for _, raw := range raws {
+		var newTag tag.Tag
+		var err error
+		if raw.Type == tag.TypeCode {
+			newTag, err = tag.Code(raw)
+		} else {
+			newTag, err = tag.Doc(raw)
+		}
+		require.NoError(t, err)
+		tags[raw.Placeholder] = newTag
+	}
+	return tags
+}
+
+func TestTags(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantErr  assert.ErrorAssertionFunc
+	}{
+		{
+			name:     "all tags sorted by placeholder",
+			template: `{{ range .Tags }}{{ .Placeholder }} {{ end }}`,
+			want:     "flag_2 flag_10 flag_code intro ",
+			wantErr:  assert.NoError,
+		},
+		{
+			name:     "with prefix",
+			template: `{{ range .Tags.WithPrefix "flag_1" }}{{ .Placeholder }}: {{ .String }}{{ end }}`,
+			want:     "flag_10: Flag 10",
+			wantErr:  assert.NoError,
+		},
+		{
+			name:     "in file",
+			template: `{{ range .Tags.InFile "cmd/*.go" }}{{ .Placeholder }} {{ .File }}:{{ .Line }}, {{ end }}`,
+			want:     "flag_2 cmd/root.go:20, flag_10 cmd/root.go:3, ",
+			wantErr:  assert.NoError,
+		},
+		{
+			name:     "invalid file pattern",
+			template: `{{ .Tags.InFile "[" }}`,
+			wantErr:  assert.Error,
+		},
+		{
+			name:     "of type",
+			template: `{{ range .Tags.OfType "code" }}{{ .Placeholder }}{{ end }}`,
+			want:     "flag_code",
+			wantErr:  assert.NoError,
+		},
+		{
+			name:     "sort by file",
+			template: `{{ range (.Tags.WithPrefix "flag_").SortBy "file" }}{{ .Placeholder }} {{ end }}`,
+			want:     "flag_10 flag_2 flag_code ",
+			wantErr:  assert.NoError,
+		},
+		{
+			name:     "sort by line",
+			template: `{{ range .Tags.SortBy "line" }}{{ .Placeholder }} {{ end }}`,
+			want:     "intro flag_10 flag_code flag_2 ",
+			wantErr:  assert.NoError,
+		},
+		{
+			name:     "unknown sort field",
+			template: `{{ .Tags.SortBy "column" }}`,
+			wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+				return assert.ErrorIs(t, err, ErrUnknownSortField, i...)
+			},
+		},
+		{
+			name:     "table of tags",
+			template: "| Flag | Line |\n| --- | --- |\n{{ range .Tags.InFile \"cmd/root.go\" }}| {{ .Placeholder }} | {{ .Line }} |\n{{ end }}",
+			want:     "| Flag | Line |\n| --- | --- |\n| flag_2 | 20 |\n| flag_10 | 3 |\n",
+			wantErr:  assert.NoError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpl := template.Must(template.New("test").Funcs(funcs).Parse(tt.template))
+
+			var buf bytes.Buffer
+			err := tpl.Execute(&buf, data{Tag: testQueryTags(t)})
+			if !tt.wantErr(t, err) || err != nil {
+				return
+			}
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
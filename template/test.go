@@ -3,6 +3,6 @@ package template
 import "text/template"
 
 func TestTemplate(fields Markdown, templateStr string) Markdown {
-	fields.template = template.Must(template.New("test").Parse(templateStr))
+	fields.template = template.Must(template.New("test").Funcs(funcs).Parse(templateStr))
 	return fields
 }
@@ -0,0 +1,128 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"time"
+
+	"github.com/aligator/nogo"
+	"github.com/spf13/afero"
+)
+
+// DefaultInterval is used if no Poll.Interval is set.
+const DefaultInterval = time.Second
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+type snapshot map[string]fileState
+
+// Poll detects changes of files by periodically comparing the modification
+// time and size of all files in the FS.
+// It does not need any OS support and therefore works with any afero.Fs.
+type Poll struct {
+	FS afero.Fs
+
+	// Interval between two scans.
+	// If it is 0, DefaultInterval is used.
+	Interval time.Duration
+
+	// IgnoreFile is the name of ignore-files (e.g. ".atwhyignore") which follow
+	// the .gitignore syntax. Files ignored by them are not watched.
+	// If it is empty, all files are watched.
+	IgnoreFile string
+}
+
+// Watch blocks until the context is done and calls onChange each time
+// at least one file was created, changed or removed since the last scan.
+func (p Poll) Watch(ctx context.Context, onChange func()) error {
+	interval := p.Interval
+	if interval == 0 {
+		interval = DefaultInterval
+	}
+
+	last, err := p.scan()
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		current, err := p.scan()
+		if err != nil {
+			return err
+		}
+
+		if !current.equals(last) {
+			onChange()
+		}
+		last = current
+	}
+}
+
+func (p Poll) scan() (snapshot, error) {
+	sysfs := afero.NewIOFS(p.FS)
+
+	n := nogo.New(nogo.DotGitRule)
+	if p.IgnoreFile != "" {
+		if err := n.AddFromFS(sysfs, p.IgnoreFile); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make(snapshot)
+	err := afero.Walk(p.FS, ".", func(path string, info fs.FileInfo, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			// The file was removed while scanning. The next scan will notice that.
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if ok, err := n.WalkFunc(sysfs, path, info.IsDir(), err); !ok {
+			return err
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		result[path] = fileState{
+			modTime: info.ModTime(),
+			size:    info.Size(),
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (s snapshot) equals(other snapshot) bool {
+	if len(s) != len(other) {
+		return false
+	}
+
+	for path, state := range s {
+		otherState, ok := other[path]
+		if !ok || !otherState.modTime.Equal(state.modTime) || otherState.size != state.size {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,119 @@
+package watcher
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func testFs() afero.Fs {
+	memFS := afero.NewMemMapFs()
+	_ = afero.WriteFile(memFS, "main.go", []byte("package main"), 0777)
+	_ = afero.WriteFile(memFS, "ignored.go", []byte("package ignored"), 0777)
+	_ = afero.WriteFile(memFS, ".atwhyignore", []byte("/ignored.go"), 0777)
+	return memFS
+}
+
+// watch starts the Poll in the background and returns a channel which
+// receives a value for each change.
+func watch(t *testing.T, p Poll) (changes chan struct{}, stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	changes = make(chan struct{}, 10)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		err := p.Watch(ctx, func() {
+			changes <- struct{}{}
+		})
+		assert.NoError(t, err)
+	}()
+
+	// Wait for the first scan.
+	time.Sleep(3 * p.Interval)
+
+	return changes, func() {
+		cancel()
+		<-done
+	}
+}
+
+func waitForChange(changes chan struct{}) bool {
+	select {
+	case <-changes:
+		return true
+	case <-time.After(200 * time.Millisecond):
+		return false
+	}
+}
+
+func TestPoll_Watch(t *testing.T) {
+	tests := []struct {
+		name       string
+		modify     func(fs afero.Fs)
+		wantChange bool
+	}{
+		{
+			name:       "nothing changed",
+			modify:     func(fs afero.Fs) {},
+			wantChange: false,
+		},
+		{
+			name: "file changed",
+			modify: func(fs afero.Fs) {
+				_ = afero.WriteFile(fs, "main.go", []byte("package main\n// @WHY tag"), 0777)
+			},
+			wantChange: true,
+		},
+		{
+			name: "file created",
+			modify: func(fs afero.Fs) {
+				_ = afero.WriteFile(fs, "new.go", []byte("package main"), 0777)
+			},
+			wantChange: true,
+		},
+		{
+			name: "file removed",
+			modify: func(fs afero.Fs) {
+				_ = fs.Remove("main.go")
+			},
+			wantChange: true,
+		},
+		{
+			name: "ignored file changed",
+			modify: func(fs afero.Fs) {
+				_ = afero.WriteFile(fs, "ignored.go", []byte("package ignored\n// @WHY tag"), 0777)
+			},
+			wantChange: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			memFS := testFs()
+			changes, stop := watch(t, Poll{
+				FS:         memFS,
+				Interval:   5 * time.Millisecond,
+				IgnoreFile: ".atwhyignore",
+			})
+			defer stop()
+
+			tt.modify(memFS)
+			assert.Equal(t, tt.wantChange, waitForChange(changes))
+		})
+	}
+
+	t.Run("without ignore file all files are watched", func(t *testing.T) {
+		memFS := testFs()
+		changes, stop := watch(t, Poll{
+			FS:       memFS,
+			Interval: 5 * time.Millisecond,
+		})
+		defer stop()
+
+		_ = afero.WriteFile(memFS, "ignored.go", []byte("package ignored\n// @WHY tag"), 0777)
+		assert.True(t, waitForChange(changes))
+	})
+}